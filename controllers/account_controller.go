@@ -0,0 +1,385 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AccountWithBalance adalah akun beserta saldo terkininya
+type AccountWithBalance struct {
+	models.Account
+	Saldo models.Money `json:"saldo"`
+	Role  string       `json:"role"` // peran user pada akun: "owner", "editor" atau "viewer"
+}
+
+// balanceFilter memilih transaksi yang dihitung pada saldo user: transaksi
+// miliknya yang belum memiliki akun dan semua transaksi pada akun yang dapat diakses
+func balanceFilter(userID primitive.ObjectID, accountIDs []primitive.ObjectID) bson.M {
+	return bson.M{"$or": []bson.M{
+		{"user_id": userID, "account_id": nil},
+		{"account_id": bson.M{"$in": accountIDs}},
+		{"to_account_id": bson.M{"$in": accountIDs}},
+	}}
+}
+
+// getAccountBalances menghitung saldo setiap akun yang dapat diakses user
+// (milik sendiri maupun akun bersama) dari saldo awal ditambah pemasukan dan
+// dikurangi pengeluaran semua anggotanya. Transfer mengurangi akun asal dan
+// menambah akun tujuan. Saldo transaksi user yang belum memiliki akun
+// dikembalikan terpisah per mata uang.
+func getAccountBalances(ctx context.Context, userID primitive.ObjectID) ([]AccountWithBalance, map[string]models.Money, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := config.GetCollection("accounts").Find(ctx, accessFilter(userID, "viewer"), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	var accounts []models.Account
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, nil, err
+	}
+
+	accountIDs := make([]primitive.ObjectID, 0, len(accounts))
+	for _, a := range accounts {
+		accountIDs = append(accountIDs, a.ID)
+	}
+
+	pipeline := []bson.M{
+		{"$match": balanceFilter(userID, accountIDs)},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"account_id":    "$account_id",
+				"to_account_id": "$to_account_id",
+				"tipe":          "$tipe",
+				"currency":      "$currency",
+			},
+			"total": sumMoney("$nominal"),
+		}},
+	}
+
+	cursor, err = config.GetCollection("transactions").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var results []struct {
+		ID struct {
+			AccountID   *primitive.ObjectID `bson:"account_id"`
+			ToAccountID *primitive.ObjectID `bson:"to_account_id"`
+			Tipe        string              `bson:"tipe"`
+			Currency    string              `bson:"currency"`
+		} `bson:"_id"`
+		Total models.Money `bson:"total"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, nil, err
+	}
+
+	mutasi := make(map[primitive.ObjectID]models.Money)
+	tanpaAkun := make(map[string]models.Money)
+	for _, r := range results {
+		total := r.Total
+		if r.ID.Tipe == "transfer" {
+			if r.ID.ToAccountID != nil {
+				mutasi[*r.ID.ToAccountID] += total
+			}
+			total = -total
+		} else if r.ID.Tipe == "pengeluaran" {
+			total = -total
+		}
+		if r.ID.AccountID == nil {
+			tanpaAkun[r.ID.Currency] += total
+			continue
+		}
+		mutasi[*r.ID.AccountID] += total
+	}
+
+	balances := make([]AccountWithBalance, 0, len(accounts))
+	for _, a := range accounts {
+		balances = append(balances, AccountWithBalance{
+			Account: a,
+			Saldo:   a.SaldoAwal + mutasi[a.ID],
+			Role:    a.RoleOf(userID),
+		})
+	}
+
+	return balances, tanpaAkun, nil
+}
+
+// findUserAccount memastikan akun dengan ID hex tersebut ada dan boleh dipakai
+// user untuk mencatat transaksi (pemilik atau anggota editor)
+func findUserAccount(ctx context.Context, userID primitive.ObjectID, accountID string) (*models.Account, error) {
+	objectID, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	var account models.Account
+	err = config.GetCollection("accounts").FindOne(ctx, withAccess(bson.M{"_id": objectID}, userID, "editor")).Decode(&account)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func CreateAccount(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.CreateAccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection := config.GetCollection("accounts")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Mata uang akun disimpan eksplisit agar tidak berubah saat mata uang dasar diganti
+	if input.Currency == "" {
+		input.Currency, err = getBaseCurrency(ctx, objectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch base currency"})
+			return
+		}
+	}
+
+	account := models.Account{
+		ID:        primitive.NewObjectID(),
+		UserID:    objectID,
+		Nama:      input.Nama,
+		Tipe:      input.Tipe,
+		SaldoAwal: input.SaldoAwal,
+		Currency:  input.Currency,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	_, err = collection.InsertOne(ctx, account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create account"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Akun berhasil dibuat",
+		"account": AccountWithBalance{Account: account, Saldo: account.SaldoAwal, Role: "owner"},
+	})
+}
+
+func GetAccounts(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	accounts, _, err := getAccountBalances(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch accounts"})
+		return
+	}
+
+	rates, err := loadRateTable(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+
+	// Total saldo dalam mata uang dasar memakai kurs terbaru
+	var totalSaldo models.Money
+	for _, a := range accounts {
+		totalSaldo += rates.convert(a.Saldo, a.Currency, time.Now())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accounts":      accounts,
+		"count":         len(accounts),
+		"total_saldo":   totalSaldo,
+		"base_currency": rates.base,
+		"missing_rates": rates.missingRates(),
+	})
+}
+
+func GetAccountByID(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	accountID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	accounts, _, err := getAccountBalances(ctx, userObjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account"})
+		return
+	}
+
+	for _, a := range accounts {
+		if a.ID == objectID {
+			c.JSON(http.StatusOK, gin.H{"account": a})
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Akun tidak ditemukan"})
+}
+
+func UpdateAccount(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	accountID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	var input models.UpdateAccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection := config.GetCollection("accounts")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"updated_at": time.Now()}
+	if input.Nama != "" {
+		update["nama"] = input.Nama
+	}
+	if input.Tipe != "" {
+		update["tipe"] = input.Tipe
+	}
+	if input.SaldoAwal != nil {
+		update["saldo_awal"] = *input.SaldoAwal
+	}
+
+	result, err := collection.UpdateOne(ctx, withAccess(bson.M{"_id": objectID}, userObjectID, "editor"), bson.M{"$set": update})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Akun tidak ditemukan"})
+		return
+	}
+
+	// Get updated account with balance
+	accounts, _, err := getAccountBalances(ctx, userObjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account"})
+		return
+	}
+
+	for _, a := range accounts {
+		if a.ID == objectID {
+			c.JSON(http.StatusOK, gin.H{
+				"message": "Akun berhasil diperbarui",
+				"account": a,
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Akun tidak ditemukan"})
+}
+
+func DeleteAccount(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	accountID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	collection := config.GetCollection("accounts")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Akun yang masih dipakai transaksi (termasuk milik anggota) tidak boleh dihapus agar saldo tetap konsisten
+	count, err := config.GetCollection("transactions").CountDocuments(ctx, bson.M{
+		"$or": []bson.M{
+			{"account_id": objectID},
+			{"to_account_id": objectID},
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "Akun masih memiliki transaksi",
+			"transaction_count": count,
+		})
+		return
+	}
+
+	// Jadwal berulang, tagihan dan cicilan yang masih memakai akun ini akan
+	// membuat transaksi pada akun yang sudah tidak ada
+	references := gin.H{}
+	for _, ref := range []struct{ collection, field string }{
+		{"recurring_transactions", "recurring_count"},
+		{"bills", "bill_count"},
+		{"installments", "installment_count"},
+	} {
+		count, err := config.GetCollection(ref.collection).CountDocuments(ctx, bson.M{"account_id": objectID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+			return
+		}
+		if count > 0 {
+			references[ref.field] = count
+		}
+	}
+	if len(references) > 0 {
+		references["error"] = "Akun masih dipakai oleh transaksi berulang, tagihan atau cicilan"
+		c.JSON(http.StatusBadRequest, references)
+		return
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Akun tidak ditemukan"})
+		return
+	}
+
+	config.GetCollection("invitations").DeleteMany(ctx, bson.M{"resource_type": "account", "resource_id": objectID, "status": "pending"})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Akun berhasil dihapus"})
+}
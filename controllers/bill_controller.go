@@ -0,0 +1,814 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureBillIndexes membuat unique index (bill_id, bill_periode) sehingga
+// satu periode tagihan tidak pernah dibayar dua kali
+func EnsureBillIndexes(ctx context.Context) error {
+	_, err := config.GetCollection("transactions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "bill_id", Value: 1}, {Key: "bill_periode", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"bill_id": bson.M{"$exists": true}}),
+	})
+	return err
+}
+
+// EnsureBillCalendarIndexes membuat unique index calendar_token pada users
+// agar feed kalender publik tidak memindai seluruh koleksi users. Sparse
+// karena sebagian besar user tidak memiliki token.
+func EnsureBillCalendarIndexes(ctx context.Context) error {
+	_, err := config.GetCollection("users").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "calendar_token", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+	return err
+}
+
+// BillWithStatus adalah tagihan beserta periode tertua yang belum dibayar
+type BillWithStatus struct {
+	models.Bill
+	NextDue *models.UpcomingBill `json:"next_due"`
+}
+
+// paidBillPeriods mengembalikan periode yang sudah dibayar untuk setiap tagihan
+func paidBillPeriods(ctx context.Context, billIDs []primitive.ObjectID) (map[primitive.ObjectID]map[string]bool, error) {
+	paid := map[primitive.ObjectID]map[string]bool{}
+	if len(billIDs) == 0 {
+		return paid, nil
+	}
+
+	opts := options.Find().SetProjection(bson.M{"bill_id": 1, "bill_periode": 1})
+	cursor, err := config.GetCollection("transactions").Find(ctx, bson.M{"bill_id": bson.M{"$in": billIDs}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var payments []models.Transaction
+	if err := cursor.All(ctx, &payments); err != nil {
+		return nil, err
+	}
+
+	for _, p := range payments {
+		if paid[*p.BillID] == nil {
+			paid[*p.BillID] = map[string]bool{}
+		}
+		paid[*p.BillID][p.BillPeriode] = true
+	}
+	return paid, nil
+}
+
+// unpaidBillPeriods mengembalikan periode tagihan yang belum dibayar dan
+// jatuh tempo paling lambat until, mulai dari start_periode
+func unpaidBillPeriods(bill models.Bill, paid map[string]bool, until, now time.Time) []models.UpcomingBill {
+	periode, err := time.Parse(models.BillPeriodeLayout, bill.StartPeriode)
+	if err != nil {
+		return nil
+	}
+
+	upcoming := []models.UpcomingBill{}
+	for due := bill.DueDateFor(periode); !due.After(until); due = bill.DueDateFor(periode) {
+		key := periode.Format(models.BillPeriodeLayout)
+		if !paid[key] {
+			upcoming = append(upcoming, models.UpcomingBill{
+				BillID:    bill.ID,
+				Nama:      bill.Nama,
+				Kategori:  bill.Kategori,
+				Amount:    bill.Amount,
+				Periode:   key,
+				DueDate:   due,
+				IsOverdue: due.Before(now),
+			})
+		}
+		periode = periode.AddDate(0, 1, 0)
+	}
+	return upcoming
+}
+
+// nextUnpaidBillPeriode mengembalikan periode tertua yang belum dibayar
+func nextUnpaidBillPeriode(bill models.Bill, paid map[string]bool) time.Time {
+	periode, _ := time.Parse(models.BillPeriodeLayout, bill.StartPeriode)
+	for paid[periode.Format(models.BillPeriodeLayout)] {
+		periode = periode.AddDate(0, 1, 0)
+	}
+	return periode
+}
+
+func withBillStatus(bill models.Bill, paid map[string]bool) BillWithStatus {
+	periode := nextUnpaidBillPeriode(bill, paid)
+	due := bill.DueDateFor(periode)
+	return BillWithStatus{
+		Bill: bill,
+		NextDue: &models.UpcomingBill{
+			BillID:    bill.ID,
+			Nama:      bill.Nama,
+			Kategori:  bill.Kategori,
+			Amount:    bill.Amount,
+			Periode:   periode.Format(models.BillPeriodeLayout),
+			DueDate:   due,
+			IsOverdue: due.Before(today(time.Now())),
+		},
+	}
+}
+
+// validateBillCategory memastikan kategori tagihan adalah kategori pengeluaran user
+func validateBillCategory(ctx context.Context, c *gin.Context, userID primitive.ObjectID, kategori string) bool {
+	valid, allowed, err := validateUserCategory(ctx, userID, "pengeluaran", kategori)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate category"})
+		return false
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":              "Kategori tidak valid",
+			"allowed_categories": allowed,
+		})
+		return false
+	}
+	return true
+}
+
+func CreateBill(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.CreateBillInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	startPeriode := time.Now().UTC().Format(models.BillPeriodeLayout)
+	if input.StartPeriode != "" {
+		if _, err := time.Parse(models.BillPeriodeLayout, input.StartPeriode); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format start_periode tidak valid. Gunakan format YYYY-MM"})
+			return
+		}
+		startPeriode = input.StartPeriode
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if !validateBillCategory(ctx, c, objectID, input.Kategori) {
+		return
+	}
+
+	var accountID *primitive.ObjectID
+	if input.AccountID != "" {
+		account, err := findUserAccount(ctx, objectID, input.AccountID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak ditemukan"})
+			return
+		}
+		accountID = &account.ID
+	}
+
+	bill := models.Bill{
+		ID:           primitive.NewObjectID(),
+		UserID:       objectID,
+		Nama:         input.Nama,
+		Kategori:     input.Kategori,
+		Amount:       input.Amount,
+		DueDay:       input.DueDay,
+		AccountID:    accountID,
+		Catatan:      input.Catatan,
+		StartPeriode: startPeriode,
+		IsActive:     true,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	_, err = config.GetCollection("bills").InsertOne(ctx, bill)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create bill"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tagihan berhasil dibuat",
+		"bill":    withBillStatus(bill, nil),
+	})
+}
+
+func GetBills(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "due_day", Value: 1}})
+	cursor, err := config.GetCollection("bills").Find(ctx, bson.M{"user_id": objectID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bills"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var bills []models.Bill
+	if err := cursor.All(ctx, &bills); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode bills"})
+		return
+	}
+
+	billIDs := make([]primitive.ObjectID, 0, len(bills))
+	for _, b := range bills {
+		billIDs = append(billIDs, b.ID)
+	}
+	paid, err := paidBillPeriods(ctx, billIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bill payments"})
+		return
+	}
+
+	result := []BillWithStatus{}
+	for _, b := range bills {
+		result = append(result, withBillStatus(b, paid[b.ID]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bills": result,
+		"count": len(result),
+	})
+}
+
+// findUserBill mengambil tagihan dari parameter :id milik user
+func findUserBill(ctx context.Context, c *gin.Context) (*models.Bill, bool) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+		return nil, false
+	}
+
+	var bill models.Bill
+	err = config.GetCollection("bills").FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&bill)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tagihan tidak ditemukan"})
+		return nil, false
+	}
+	return &bill, true
+}
+
+func GetBillByID(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	bill, ok := findUserBill(ctx, c)
+	if !ok {
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "bill_periode", Value: -1}})
+	cursor, err := config.GetCollection("transactions").Find(ctx, bson.M{"bill_id": bill.ID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bill payments"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	payments := []models.Transaction{}
+	if err := cursor.All(ctx, &payments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode bill payments"})
+		return
+	}
+
+	paid := map[string]bool{}
+	for _, p := range payments {
+		paid[p.BillPeriode] = true
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bill":     withBillStatus(*bill, paid),
+		"payments": payments,
+	})
+}
+
+// GetUpcomingBills - Tagihan yang belum dibayar dan jatuh tempo dalam N hari ke depan
+func GetUpcomingBills(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	days := 7
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days harus berupa angka 1-365"})
+			return
+		}
+		days = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.GetCollection("bills").Find(ctx, bson.M{"user_id": objectID, "is_active": true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bills"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var bills []models.Bill
+	if err := cursor.All(ctx, &bills); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode bills"})
+		return
+	}
+
+	billIDs := make([]primitive.ObjectID, 0, len(bills))
+	for _, b := range bills {
+		billIDs = append(billIDs, b.ID)
+	}
+	paid, err := paidBillPeriods(ctx, billIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bill payments"})
+		return
+	}
+
+	// Tagihan yang sudah lewat jatuh tempo tetap ditampilkan sampai dibayar
+	now := today(time.Now())
+	until := now.AddDate(0, 0, days)
+	upcoming := []models.UpcomingBill{}
+	var total models.Money
+	for _, b := range bills {
+		for _, u := range unpaidBillPeriods(b, paid[b.ID], until, now) {
+			upcoming = append(upcoming, u)
+			total += u.Amount
+		}
+	}
+
+	sort.Slice(upcoming, func(i, j int) bool {
+		return upcoming[i].DueDate.Before(upcoming[j].DueDate)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":     days,
+		"upcoming": upcoming,
+		"count":    len(upcoming),
+		"total":    total,
+	})
+}
+
+func UpdateBill(c *gin.Context) {
+	var input models.UpdateBillInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	bill, ok := findUserBill(ctx, c)
+	if !ok {
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+	if input.Nama != "" {
+		set["nama"] = input.Nama
+	}
+	if input.Kategori != "" {
+		if !validateBillCategory(ctx, c, bill.UserID, input.Kategori) {
+			return
+		}
+		set["kategori"] = input.Kategori
+	}
+	if input.Amount > 0 {
+		set["amount"] = input.Amount
+	}
+	if input.DueDay > 0 {
+		set["due_day"] = input.DueDay
+	}
+	if input.AccountID != nil {
+		if *input.AccountID == "" {
+			unset["account_id"] = ""
+		} else {
+			account, err := findUserAccount(ctx, bill.UserID, *input.AccountID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak ditemukan"})
+				return
+			}
+			set["account_id"] = account.ID
+		}
+	}
+	if input.Catatan != nil {
+		set["catatan"] = *input.Catatan
+	}
+	if input.IsActive != nil {
+		set["is_active"] = *input.IsActive
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var updated models.Bill
+	err := config.GetCollection("bills").FindOneAndUpdate(ctx,
+		bson.M{"_id": bill.ID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update bill"})
+		return
+	}
+
+	paid, err := paidBillPeriods(ctx, []primitive.ObjectID{updated.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bill payments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tagihan berhasil diperbarui",
+		"bill":    withBillStatus(updated, paid[updated.ID]),
+	})
+}
+
+func DeleteBill(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.GetCollection("bills").DeleteOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete bill"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tagihan tidak ditemukan"})
+		return
+	}
+
+	// Transaksi pembayaran tetap disimpan, namun tidak lagi terhubung dengan tagihan
+	config.GetCollection("transactions").UpdateMany(ctx,
+		bson.M{"bill_id": objectID},
+		bson.M{"$unset": bson.M{"bill_id": "", "bill_periode": ""}},
+	)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tagihan berhasil dihapus"})
+}
+
+// PayBill - Menandai satu periode tagihan lunas dengan mencatat transaksi pengeluaran
+func PayBill(c *gin.Context) {
+	var input models.PayBillInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tanggal := today(time.Now())
+	if input.Tanggal != "" {
+		parsed, err := time.Parse("2006-01-02", input.Tanggal)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format tanggal tidak valid"})
+			return
+		}
+		tanggal = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	bill, ok := findUserBill(ctx, c)
+	if !ok {
+		return
+	}
+	if !bill.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tagihan tidak aktif, aktifkan tagihan terlebih dahulu"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	var periode string
+	if input.Periode != "" {
+		parsed, err := time.Parse(models.BillPeriodeLayout, input.Periode)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format periode tidak valid. Gunakan format YYYY-MM"})
+			return
+		}
+		if parsed.Format(models.BillPeriodeLayout) < bill.StartPeriode {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Periode sebelum start_periode tagihan"})
+			return
+		}
+		periode = parsed.Format(models.BillPeriodeLayout)
+	} else {
+		paid, err := paidBillPeriods(ctx, []primitive.ObjectID{bill.ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bill payments"})
+			return
+		}
+		periode = nextUnpaidBillPeriode(*bill, paid[bill.ID]).Format(models.BillPeriodeLayout)
+	}
+
+	// Akun tagihan diperiksa ulang karena akses ke akun bersama bisa sudah dicabut
+	var accountID *primitive.ObjectID
+	if input.AccountID != "" {
+		account, err := findUserAccount(ctx, userObjectID, input.AccountID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak ditemukan"})
+			return
+		}
+		accountID = &account.ID
+	} else if bill.AccountID != nil {
+		account, err := findUserAccount(ctx, userObjectID, bill.AccountID.Hex())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tagihan tidak ditemukan, pilih account_id lain"})
+			return
+		}
+		accountID = &account.ID
+	}
+
+	nominal := bill.Amount
+	if input.Nominal > 0 {
+		nominal = input.Nominal
+	}
+
+	currency, err := accountCurrency(ctx, userObjectID, accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch currency"})
+		return
+	}
+
+	catatan := input.Catatan
+	if catatan == "" {
+		catatan = fmt.Sprintf("Pembayaran tagihan %s periode %s", bill.Nama, periode)
+	}
+
+	transaction := models.Transaction{
+		ID:          primitive.NewObjectID(),
+		UserID:      userObjectID,
+		AccountID:   accountID,
+		Tipe:        "pengeluaran",
+		Nominal:     nominal,
+		Currency:    currency,
+		Kategori:    bill.Kategori,
+		Catatan:     catatan,
+		Tanggal:     tanggal,
+		BillID:      &bill.ID,
+		BillPeriode: periode,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	// Unique index (bill_id, bill_periode) mencegah satu periode dibayar dua kali
+	_, err = config.GetCollection("transactions").InsertOne(ctx, transaction)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Tagihan periode " + periode + " sudah dibayar"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pay bill"})
+		return
+	}
+
+	response := gin.H{
+		"message":     "Tagihan berhasil dibayar",
+		"periode":     periode,
+		"transaction": transaction,
+	}
+
+	// Peringatan budget tidak menggagalkan transaksi yang sudah tersimpan
+	if warnings, _ := checkBudgetAlerts(ctx, userObjectID, transaction, nil); len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+
+	if contributions := applyGoalRules(ctx, transaction); len(contributions) > 0 {
+		response["goal_contributions"] = contributions
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateBillCalendarToken - Membuat (atau mengganti) token rahasia feed kalender tagihan
+func CreateBillCalendarToken(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate calendar token"})
+		return
+	}
+	token := hex.EncodeToString(raw)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = config.GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$set": bson.M{"calendar_token": token, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save calendar token"})
+		return
+	}
+
+	scheme := "https"
+	if c.Request.TLS == nil && c.GetHeader("X-Forwarded-Proto") != "https" {
+		scheme = "http"
+	}
+	path := "/api/bills/calendar/" + token + ".ics"
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Token kalender berhasil dibuat, token lama tidak berlaku lagi",
+		"url":     scheme + "://" + c.Request.Host + path,
+	})
+}
+
+// DeleteBillCalendarToken - Menonaktifkan feed kalender tagihan
+func DeleteBillCalendarToken(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = config.GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$unset": bson.M{"calendar_token": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Feed kalender dinonaktifkan"})
+}
+
+// billCalendarMonths adalah jumlah bulan ke depan yang dimuat di feed kalender
+const billCalendarMonths = 12
+
+// GetBillCalendar - Feed ICS tagihan untuk aplikasi kalender (publik, memakai token rahasia)
+func GetBillCalendar(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	if token == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kalender tidak ditemukan"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := config.GetCollection("users").FindOne(ctx, bson.M{"calendar_token": token}).Decode(&user)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kalender tidak ditemukan"})
+		return
+	}
+
+	cursor, err := config.GetCollection("bills").Find(ctx, bson.M{"user_id": user.ID, "is_active": true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bills"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var bills []models.Bill
+	if err := cursor.All(ctx, &bills); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode bills"})
+		return
+	}
+
+	billIDs := make([]primitive.ObjectID, 0, len(bills))
+	for _, b := range bills {
+		billIDs = append(billIDs, b.ID)
+	}
+	paid, err := paidBillPeriods(ctx, billIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bill payments"})
+		return
+	}
+
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(buildBillCalendar(bills, paid, time.Now())))
+}
+
+// buildBillCalendar membuat satu VEVENT seharian untuk setiap periode tagihan
+// mulai bulan lalu sampai billCalendarMonths ke depan, dengan pengingat
+// sehari sebelum jatuh tempo
+func buildBillCalendar(bills []models.Bill, paid map[primitive.ObjectID]map[string]bool, now time.Time) string {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(s)
+		b.WriteString("\r\n")
+	}
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//DompetKu//Tagihan//ID")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:Tagihan DompetKu")
+
+	for _, bill := range bills {
+		start, err := time.Parse(models.BillPeriodeLayout, bill.StartPeriode)
+		if err != nil {
+			continue
+		}
+		periode := from
+		if start.After(periode) {
+			periode = start
+		}
+
+		for i := 0; i < billCalendarMonths; i++ {
+			key := periode.Format(models.BillPeriodeLayout)
+			due := bill.DueDateFor(periode)
+
+			summary := fmt.Sprintf("Tagihan %s (Rp%s)", bill.Nama, bill.Amount)
+			if paid[bill.ID][key] {
+				summary = "[Lunas] " + summary
+			}
+
+			line("BEGIN:VEVENT")
+			line("UID:" + bill.ID.Hex() + "-" + key + "@dompetku")
+			line("DTSTAMP:" + stamp)
+			line("DTSTART;VALUE=DATE:" + due.Format("20060102"))
+			line("DTEND;VALUE=DATE:" + due.AddDate(0, 0, 1).Format("20060102"))
+			line("SUMMARY:" + escapeICS(summary))
+			line("DESCRIPTION:" + escapeICS("Kategori: "+bill.Kategori+"\n"+bill.Catatan))
+			if !paid[bill.ID][key] {
+				line("BEGIN:VALARM")
+				line("ACTION:DISPLAY")
+				line("DESCRIPTION:" + escapeICS(summary))
+				line("TRIGGER:-P1D")
+				line("END:VALARM")
+			}
+			line("END:VEVENT")
+
+			periode = periode.AddDate(0, 1, 0)
+		}
+	}
+
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICS meng-escape karakter khusus pada nilai TEXT iCalendar (RFC 5545)
+func escapeICS(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
@@ -0,0 +1,497 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BudgetStatus adalah budget beserta pemakaiannya pada bulan tersebut
+type BudgetStatus struct {
+	models.Budget
+	Spent      models.Money `json:"spent"`
+	Remaining  models.Money `json:"remaining"`
+	Percentage float64      `json:"percentage"`
+}
+
+// parseBulan memvalidasi bulan berformat YYYY-MM (default bulan ini) dan
+// mengembalikan rentang tanggalnya
+func parseBulan(bulan string) (string, time.Time, time.Time, error) {
+	if bulan == "" {
+		bulan = time.Now().Format("2006-01")
+	}
+
+	start, err := time.Parse("2006-01", bulan)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, err
+	}
+	return bulan, start, start.AddDate(0, 1, 0), nil
+}
+
+// getBudgetStatuses menghitung pengeluaran, sisa dan persentase pemakaian
+// setiap budget user pada satu bulan. Pengeluaran subkategori ikut dihitung
+// ke budget kategori induknya, dan pengeluaran dalam mata uang lain
+// dikonversi ke mata uang dasar dengan kurs pada tanggal transaksi.
+func getBudgetStatuses(ctx context.Context, userID primitive.ObjectID, rates *rateTable, bulan string, start, end time.Time) ([]BudgetStatus, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "kategori", Value: 1}})
+	cursor, err := config.GetCollection("budgets").Find(ctx, bson.M{"user_id": userID, "bulan": bulan}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var budgets []models.Budget
+	if err := cursor.All(ctx, &budgets); err != nil {
+		return nil, err
+	}
+
+	statuses := []BudgetStatus{}
+	if len(budgets) == 0 {
+		return statuses, nil
+	}
+
+	// Aggregate pengeluaran per kategori pada bulan tersebut, split dihitung ke kategorinya masing-masing
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"user_id": userID,
+			"tipe":    "pengeluaran",
+			"tanggal": bson.M{"$gte": start, "$lt": end},
+		}},
+	}
+	pipeline = append(pipeline, splitLineStages()...)
+	pipeline = append(pipeline, bson.M{"$group": bson.M{
+		"_id":   withCurrencyKey(bson.M{"kategori": "$lines.kategori"}, rates.base),
+		"total": sumMoney("$lines.nominal"),
+	}})
+
+	cursor, err = config.GetCollection("transactions").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []struct {
+		ID struct {
+			Kategori      string `bson:"kategori"`
+			currencyGroup `bson:",inline"`
+		} `bson:"_id"`
+		Total models.Money `bson:"total"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	parents, err := getCategoryParents(ctx, userID, "pengeluaran")
+	if err != nil {
+		return nil, err
+	}
+
+	spent := make(map[string]models.Money)
+	for _, r := range results {
+		total := rates.convert(r.Total, r.ID.Currency, r.ID.Tanggal)
+		spent[r.ID.Kategori] += total
+		if parent, ok := parents[r.ID.Kategori]; ok {
+			spent[parent] += total
+		}
+	}
+
+	for _, b := range budgets {
+		percentage := 0.0
+		if b.Limit > 0 {
+			percentage = spent[b.Kategori].Ratio(b.Limit) * 100
+		}
+		statuses = append(statuses, BudgetStatus{
+			Budget:     b,
+			Spent:      spent[b.Kategori],
+			Remaining:  b.Limit - spent[b.Kategori],
+			Percentage: percentage,
+		})
+	}
+
+	return statuses, nil
+}
+
+func CreateBudget(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.CreateBudgetInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bulan, _, _, err := parseBulan(input.Bulan)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format bulan tidak valid. Gunakan format YYYY-MM"})
+		return
+	}
+
+	collection := config.GetCollection("budgets")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	valid, allowed, err := validateUserCategory(ctx, objectID, "pengeluaran", input.Kategori)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate category"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":              "Kategori tidak valid",
+			"allowed_categories": allowed,
+		})
+		return
+	}
+
+	// Satu kategori hanya boleh memiliki satu budget per bulan
+	count, err := collection.CountDocuments(ctx, bson.M{"user_id": objectID, "kategori": input.Kategori, "bulan": bulan})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create budget"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Budget untuk kategori dan bulan ini sudah ada"})
+		return
+	}
+
+	budget := models.Budget{
+		ID:        primitive.NewObjectID(),
+		UserID:    objectID,
+		Kategori:  input.Kategori,
+		Bulan:     bulan,
+		Limit:     input.Limit,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	_, err = collection.InsertOne(ctx, budget)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create budget"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Budget berhasil dibuat",
+		"budget":  budget,
+	})
+}
+
+func GetBudgets(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	collection := config.GetCollection("budgets")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Filter by bulan if provided
+	filter := bson.M{"user_id": objectID}
+	if bulan := c.Query("bulan"); bulan != "" {
+		filter["bulan"] = bulan
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "bulan", Value: -1}, {Key: "kategori", Value: 1}})
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch budgets"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var budgets []models.Budget
+	if err := cursor.All(ctx, &budgets); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode budgets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budgets": budgets,
+		"count":   len(budgets),
+	})
+}
+
+func UpdateBudget(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	budgetID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(budgetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget ID"})
+		return
+	}
+
+	var input models.UpdateBudgetInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection := config.GetCollection("budgets")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}, bson.M{
+		"$set": bson.M{
+			"limit":      input.Limit,
+			"updated_at": time.Now(),
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update budget"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Budget tidak ditemukan"})
+		return
+	}
+
+	// Get updated budget
+	var budget models.Budget
+	collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&budget)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Budget berhasil diperbarui",
+		"budget":  budget,
+	})
+}
+
+func DeleteBudget(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	budgetID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(budgetID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget ID"})
+		return
+	}
+
+	collection := config.GetCollection("budgets")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete budget"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Budget tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Budget berhasil dihapus"})
+}
+
+// GetBudgetStatus returns spent, remaining and percentage used per budget for one month
+func GetBudgetStatus(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	bulan, start, end, err := parseBulan(c.Query("bulan"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format bulan tidak valid. Gunakan format YYYY-MM"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rates, err := loadRateTable(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+
+	statuses, err := getBudgetStatuses(ctx, objectID, rates, bulan, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get budget status"})
+		return
+	}
+
+	var totalLimit, totalSpent models.Money
+	for _, s := range statuses {
+		totalLimit += s.Limit
+		totalSpent += s.Spent
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bulan":         bulan,
+		"budgets":       statuses,
+		"total_limit":   totalLimit,
+		"total_spent":   totalSpent,
+		"base_currency": rates.base,
+		"missing_rates": rates.missingRates(),
+	})
+}
+
+// migrateBudgetCategories memindahkan budget dari kategori di from ke kategori to.
+// Bila pada bulan yang sama sudah ada budget kategori to, limitnya dijumlahkan
+// dan budget lama dihapus.
+func migrateBudgetCategories(ctx context.Context, userID primitive.ObjectID, from []string, to string) (int64, int64, error) {
+	collection := config.GetCollection("budgets")
+
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID, "kategori": bson.M{"$in": from}})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var budgets []models.Budget
+	if err := cursor.All(ctx, &budgets); err != nil {
+		return 0, 0, err
+	}
+
+	var modified int64
+	for _, b := range budgets {
+		result, err := collection.UpdateOne(ctx,
+			bson.M{"user_id": userID, "kategori": to, "bulan": b.Bulan},
+			bson.M{
+				"$inc": bson.M{"limit": b.Limit},
+				"$set": bson.M{"updated_at": time.Now()},
+			},
+		)
+		if err != nil {
+			return int64(len(budgets)), modified, err
+		}
+
+		if result.MatchedCount > 0 {
+			_, err = collection.DeleteOne(ctx, bson.M{"_id": b.ID})
+		} else {
+			_, err = collection.UpdateOne(ctx, bson.M{"_id": b.ID}, bson.M{
+				"$set": bson.M{"kategori": to, "updated_at": time.Now()},
+			})
+		}
+		if err != nil {
+			return int64(len(budgets)), modified, err
+		}
+		modified++
+	}
+
+	return int64(len(budgets)), modified, nil
+}
+
+// budgetAlertThresholds adalah persentase pemakaian budget yang memicu peringatan
+var budgetAlertThresholds = []int{100, 80}
+
+// checkBudgetAlerts memeriksa budget bulan transaksi yang pemakaiannya naik
+// karena transaksi tersebut. previous adalah isi transaksi sebelum diubah
+// (nil untuk transaksi baru). Peringatan hanya dibuat jika transaksi ini yang
+// membuat pemakaian melewati 80% atau 100%, dan notifikasi dicatat sekali per
+// budget, bulan, limit dan threshold sehingga bisa dibaca dari perangkat lain.
+func checkBudgetAlerts(ctx context.Context, userID primitive.ObjectID, transaction models.Transaction, previous *models.Transaction) ([]string, error) {
+	if transaction.Tipe != "pengeluaran" {
+		return nil, nil
+	}
+
+	bulan, start, end, err := parseBulan(transaction.Tanggal.Format("2006-01"))
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := loadRateTable(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := getBudgetStatuses(ctx, userID, rates, bulan, start, end)
+	if err != nil || len(statuses) == 0 {
+		return nil, err
+	}
+
+	parents, err := getCategoryParents(ctx, userID, "pengeluaran")
+	if err != nil {
+		return nil, err
+	}
+
+	// budgetAmount adalah bagian transaksi t yang dihitung pada budget kategori
+	budgetAmount := func(t *models.Transaction, kategori string) models.Money {
+		if t == nil || t.Tipe != "pengeluaran" || t.Tanggal.Format("2006-01") != bulan {
+			return 0
+		}
+		var total models.Money
+		for _, c := range t.Categories() {
+			if c == kategori || parents[c] == kategori {
+				total += t.AmountIn(c)
+			}
+		}
+		return rates.convert(total, t.Currency, t.Tanggal)
+	}
+
+	warnings := []string{}
+	for _, s := range statuses {
+		added := budgetAmount(&transaction, s.Kategori) - budgetAmount(previous, s.Kategori)
+		if added <= 0 || s.Limit <= 0 {
+			continue
+		}
+		before := (s.Spent - added).Ratio(s.Limit) * 100
+
+		for _, threshold := range budgetAlertThresholds {
+			if s.Percentage < float64(threshold) {
+				continue
+			}
+			if before >= float64(threshold) {
+				// Threshold ini sudah terlewati sebelum transaksi ini
+				break
+			}
+
+			judul := fmt.Sprintf("Budget %s hampir habis", s.Kategori)
+			pesan := fmt.Sprintf("Pengeluaran %s bulan %s sudah mencapai %.0f%% dari budget", s.Kategori, s.Bulan, s.Percentage)
+			if threshold >= 100 {
+				judul = fmt.Sprintf("Budget %s terlampaui", s.Kategori)
+				pesan = fmt.Sprintf("Pengeluaran %s bulan %s sudah melebihi budget (%.0f%%)", s.Kategori, s.Bulan, s.Percentage)
+			}
+			warnings = append(warnings, pesan)
+
+			// Upsert agar notifikasi untuk budget, limit dan threshold yang sama hanya
+			// dibuat sekali. Setelah limit dinaikkan, melewati threshold lagi memberi notifikasi baru.
+			budgetID := s.ID
+			_, err := config.GetCollection("notifications").UpdateOne(ctx,
+				bson.M{"user_id": userID, "tipe": "budget_alert", "budget_id": budgetID, "bulan": s.Bulan, "limit": s.Limit, "threshold": threshold},
+				bson.M{"$setOnInsert": models.Notification{
+					ID:        primitive.NewObjectID(),
+					UserID:    userID,
+					Tipe:      "budget_alert",
+					Judul:     judul,
+					Pesan:     pesan,
+					BudgetID:  &budgetID,
+					Bulan:     s.Bulan,
+					Limit:     s.Limit,
+					Threshold: threshold,
+					IsRead:    false,
+					CreatedAt: time.Now(),
+				}},
+				options.Update().SetUpsert(true),
+			)
+			if err != nil {
+				return warnings, err
+			}
+			break
+		}
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,740 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultCategories memetakan tipe transaksi ke kategori bawaan dan field
+// penanda di dokumen user yang menyatakan kategori tersebut sudah disalin
+var defaultCategories = map[string]struct {
+	seedFlag string
+	names    []string
+}{
+	"pengeluaran": {seedFlag: "categories_seeded", names: models.AllowedCategories},
+	"pemasukan":   {seedFlag: "income_categories_seeded", names: models.DefaultIncomeCategories},
+}
+
+// ensureDefaultCategories menyalin kategori bawaan untuk satu tipe ke koleksi
+// categories user tepat satu kali. Penanda di dokumen user dipasang secara
+// atomik sehingga permintaan yang berjalan bersamaan tidak menyalin dua kali.
+func ensureDefaultCategories(ctx context.Context, userID primitive.ObjectID, tipe string) error {
+	defaults := defaultCategories[tipe]
+
+	result, err := config.GetCollection("users").UpdateOne(ctx,
+		bson.M{"_id": userID, defaults.seedFlag: bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{defaults.seedFlag: true}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var docs []interface{}
+	for _, nama := range defaults.names {
+		docs = append(docs, models.Category{
+			ID:        primitive.NewObjectID(),
+			UserID:    userID,
+			Nama:      nama,
+			Tipe:      tipe,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+
+	_, err = config.GetCollection("categories").InsertMany(ctx, docs)
+	if err != nil {
+		// Lepas penanda agar penyalinan dicoba lagi pada permintaan berikutnya
+		config.GetCollection("users").UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{defaults.seedFlag: false}})
+		return err
+	}
+	return nil
+}
+
+// ensureCategory menambahkan kategori nama ke kategori user jika belum ada.
+// Dipakai untuk kategori transaksi yang dibuat sistem (misalnya Utang Piutang)
+// agar kategori tersebut dapat di-rename, digabung dan diberi budget.
+func ensureCategory(ctx context.Context, userID primitive.ObjectID, tipe, nama string) error {
+	if err := ensureDefaultCategories(ctx, userID, tipe); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err := config.GetCollection("categories").UpdateOne(ctx,
+		bson.M{"user_id": userID, "tipe": tipe, "nama": nama},
+		bson.M{"$setOnInsert": bson.M{"created_at": now, "updated_at": now}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// getUserCategories mengembalikan kategori milik user untuk tipe transaksi tertentu
+func getUserCategories(ctx context.Context, userID primitive.ObjectID, tipe string) ([]models.Category, error) {
+	if err := ensureDefaultCategories(ctx, userID, tipe); err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := config.GetCollection("categories").Find(ctx, bson.M{"user_id": userID, "tipe": tipe}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := []models.Category{}
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// categoryNames mengambil nama dari daftar kategori
+func categoryNames(categories []models.Category) []string {
+	names := make([]string, 0, len(categories))
+	for _, cat := range categories {
+		names = append(names, cat.Nama)
+	}
+	return names
+}
+
+// validateUserCategory memeriksa kategori terhadap kategori milik user dan
+// mengembalikan daftar nama kategori yang diizinkan
+func validateUserCategory(ctx context.Context, userID primitive.ObjectID, tipe, kategori string) (bool, []string, error) {
+	categories, err := getUserCategories(ctx, userID, tipe)
+	if err != nil {
+		return false, nil, err
+	}
+
+	names := categoryNames(categories)
+	for _, nama := range names {
+		if nama == kategori {
+			return true, names, nil
+		}
+	}
+	return false, names, nil
+}
+
+// getCategoryParents memetakan nama setiap subkategori ke nama kategori induknya
+func getCategoryParents(ctx context.Context, userID primitive.ObjectID, tipe string) (map[string]string, error) {
+	categories, err := getUserCategories(ctx, userID, tipe)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[primitive.ObjectID]string)
+	for _, cat := range categories {
+		names[cat.ID] = cat.Nama
+	}
+
+	parents := make(map[string]string)
+	for _, cat := range categories {
+		if cat.ParentID != nil {
+			if parent, ok := names[*cat.ParentID]; ok {
+				parents[cat.Nama] = parent
+			}
+		}
+	}
+	return parents, nil
+}
+
+// findParentCategory memastikan kategori induk ada, bertipe sama dan merupakan
+// kategori utama. Subkategori hanya boleh satu tingkat.
+func findParentCategory(ctx context.Context, userID primitive.ObjectID, tipe, parentID string) (*models.Category, string) {
+	objectID, err := primitive.ObjectIDFromHex(parentID)
+	if err != nil {
+		return nil, "Invalid parent ID"
+	}
+
+	var parent models.Category
+	err = config.GetCollection("categories").FindOne(ctx, bson.M{"_id": objectID, "user_id": userID}).Decode(&parent)
+	if err != nil {
+		return nil, "Kategori induk tidak ditemukan"
+	}
+	if parent.Tipe != tipe {
+		return nil, "Kategori induk harus bertipe " + tipe
+	}
+	if parent.ParentID != nil {
+		return nil, "Subkategori tidak dapat menjadi kategori induk"
+	}
+	return &parent, ""
+}
+
+// CategoryMigrationResult melaporkan dokumen yang terdampak oleh rename atau merge kategori
+type CategoryMigrationResult struct {
+	Mode                 string `json:"mode"` // "rename" atau "merge"
+	DryRun               bool   `json:"dry_run"`
+	TransactionsMatched  int64  `json:"transactions_matched"`
+	TransactionsModified int64  `json:"transactions_modified"`
+	BudgetsMatched       int64  `json:"budgets_matched"`
+	BudgetsModified      int64  `json:"budgets_modified"`
+	RecurringMatched     int64  `json:"recurring_matched"`
+	RecurringModified    int64  `json:"recurring_modified"`
+	GoalRulesMatched     int64  `json:"goal_rules_matched"`
+	GoalRulesModified    int64  `json:"goal_rules_modified"`
+	InstallmentsMatched  int64  `json:"installments_matched"`
+	InstallmentsModified int64  `json:"installments_modified"`
+	BillsMatched         int64  `json:"bills_matched"`
+	BillsModified        int64  `json:"bills_modified"`
+	CategoriesRemoved    int64  `json:"categories_removed"`
+}
+
+// migrateKategoriField mengganti field kategori yang ada di from menjadi to
+// pada dokumen collection yang cocok dengan filter. Dengan dryRun dokumen
+// hanya dihitung.
+func migrateKategoriField(ctx context.Context, collection string, filter bson.M, from []string, to string, dryRun bool) (int64, int64, error) {
+	filter["kategori"] = bson.M{"$in": from}
+	if dryRun {
+		matched, err := config.GetCollection(collection).CountDocuments(ctx, filter)
+		return matched, 0, err
+	}
+
+	result, err := config.GetCollection(collection).UpdateMany(ctx, filter, bson.M{
+		"$set": bson.M{"kategori": to, "updated_at": time.Now()},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return result.MatchedCount, result.ModifiedCount, nil
+}
+
+// migrateCategories memindahkan transaksi, budget, transaksi berulang, aturan
+// tabungan goal, cicilan dan tagihan dengan kategori di from ke kategori to.
+// Jika to sudah ada maka kategori di from digabung (merge) ke to, jika belum
+// maka kategori pertama di from diganti namanya (rename). Transaksi diperbarui
+// lebih dulu sehingga permintaan yang gagal di tengah jalan aman diulang.
+func migrateCategories(ctx context.Context, userID primitive.ObjectID, tipe string, from []string, to string, dryRun bool) (*CategoryMigrationResult, error) {
+	if err := ensureDefaultCategories(ctx, userID, tipe); err != nil {
+		return nil, err
+	}
+
+	categoryCollection := config.GetCollection("categories")
+	transactionCollection := config.GetCollection("transactions")
+
+	toCount, err := categoryCollection.CountDocuments(ctx, bson.M{"user_id": userID, "tipe": tipe, "nama": to})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CategoryMigrationResult{Mode: "rename", DryRun: dryRun}
+
+	// Aturan persentase memfilter kategori pemasukan, aturan pembulatan kategori pengeluaran
+	goalRuleFilter := bson.M{"user_id": userID, "tipe": "persentase"}
+	if tipe == "pengeluaran" {
+		goalRuleFilter["tipe"] = "pembulatan"
+	}
+	if toCount > 0 {
+		result.Mode = "merge"
+	}
+
+	transactionFilter := bson.M{"user_id": userID, "tipe": tipe, "$or": []bson.M{
+		{"kategori": bson.M{"$in": from}},
+		{"splits.kategori": bson.M{"$in": from}},
+	}}
+	categoryFilter := bson.M{"user_id": userID, "tipe": tipe, "nama": bson.M{"$in": from}}
+
+	if dryRun {
+		result.TransactionsMatched, err = transactionCollection.CountDocuments(ctx, transactionFilter)
+		if err != nil {
+			return nil, err
+		}
+		if tipe == "pengeluaran" {
+			result.BudgetsMatched, err = config.GetCollection("budgets").CountDocuments(ctx, bson.M{"user_id": userID, "kategori": bson.M{"$in": from}})
+			if err != nil {
+				return nil, err
+			}
+			result.InstallmentsMatched, _, err = migrateKategoriField(ctx, "installments", bson.M{"user_id": userID}, from, to, true)
+			if err != nil {
+				return nil, err
+			}
+			result.BillsMatched, _, err = migrateKategoriField(ctx, "bills", bson.M{"user_id": userID}, from, to, true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		result.RecurringMatched, _, err = migrateKategoriField(ctx, "recurring_transactions", bson.M{"user_id": userID, "tipe": tipe}, from, to, true)
+		if err != nil {
+			return nil, err
+		}
+		result.GoalRulesMatched, _, err = migrateKategoriField(ctx, "goal_rules", goalRuleFilter, from, to, true)
+		if err != nil {
+			return nil, err
+		}
+		result.CategoriesRemoved, err = categoryCollection.CountDocuments(ctx, categoryFilter)
+		if err != nil {
+			return nil, err
+		}
+		if result.Mode == "rename" && result.CategoriesRemoved > 0 {
+			result.CategoriesRemoved-- // satu kategori diganti namanya, bukan dihapus
+		}
+		return result, nil
+	}
+
+	var fromCategories []models.Category
+	cursor, err := categoryCollection.Find(ctx, categoryFilter)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &fromCategories); err != nil {
+		return nil, err
+	}
+
+	// Kategori transaksi dan kategori setiap split diganti dalam satu update
+	renamed := func(field string) bson.M {
+		return bson.M{"$cond": bson.A{bson.M{"$in": bson.A{field, from}}, to, field}}
+	}
+	updateResult, err := transactionCollection.UpdateMany(ctx, transactionFilter, bson.A{bson.M{"$set": bson.M{
+		"kategori": renamed("$kategori"),
+		"splits": bson.M{"$cond": bson.A{
+			bson.M{"$isArray": "$splits"},
+			bson.M{"$map": bson.M{
+				"input": "$splits",
+				"as":    "s",
+				"in":    bson.M{"$mergeObjects": bson.A{"$$s", bson.M{"kategori": renamed("$$s.kategori")}}},
+			}},
+			"$$REMOVE",
+		}},
+		"updated_at": time.Now(),
+	}}})
+	if err != nil {
+		return nil, err
+	}
+	result.TransactionsMatched = updateResult.MatchedCount
+	result.TransactionsModified = updateResult.ModifiedCount
+
+	// Budget, cicilan dan tagihan hanya ada untuk kategori pengeluaran
+	if tipe == "pengeluaran" {
+		result.BudgetsMatched, result.BudgetsModified, err = migrateBudgetCategories(ctx, userID, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		// Pembayaran cicilan dikenali dari kategorinya
+		result.InstallmentsMatched, result.InstallmentsModified, err = migrateKategoriField(ctx, "installments", bson.M{"user_id": userID}, from, to, false)
+		if err != nil {
+			return nil, err
+		}
+
+		// Pembayaran tagihan berikutnya dicatat dengan kategori tagihan
+		result.BillsMatched, result.BillsModified, err = migrateKategoriField(ctx, "bills", bson.M{"user_id": userID}, from, to, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Jadwal berulang harus ikut pindah agar tidak terus membuat transaksi di kategori lama
+	result.RecurringMatched, result.RecurringModified, err = migrateKategoriField(ctx, "recurring_transactions", bson.M{"user_id": userID, "tipe": tipe}, from, to, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Aturan goal dengan kategori lama tidak akan pernah berjalan lagi
+	result.GoalRulesMatched, result.GoalRulesModified, err = migrateKategoriField(ctx, "goal_rules", goalRuleFilter, from, to, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Mode == "rename" {
+		renameResult := categoryCollection.FindOneAndUpdate(ctx, categoryFilter, bson.M{
+			"$set": bson.M{"nama": to, "updated_at": time.Now()},
+		})
+		if renameResult.Err() == mongo.ErrNoDocuments {
+			// Kategori asal hanya tersisa di transaksi lama, buat kategori tujuan baru
+			_, err = categoryCollection.InsertOne(ctx, models.Category{
+				ID:        primitive.NewObjectID(),
+				UserID:    userID,
+				Nama:      to,
+				Tipe:      tipe,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			})
+			if err != nil {
+				return nil, err
+			}
+		} else if renameResult.Err() != nil {
+			return nil, renameResult.Err()
+		}
+	}
+
+	// Subkategori dari kategori yang dihapus dipindahkan ke kategori tujuan
+	// (atau ke induknya bila tujuan sendiri adalah subkategori)
+	var target models.Category
+	err = categoryCollection.FindOne(ctx, bson.M{"user_id": userID, "tipe": tipe, "nama": to}).Decode(&target)
+	if err != nil {
+		return nil, err
+	}
+
+	var removedIDs []primitive.ObjectID
+	for _, cat := range fromCategories {
+		if cat.ID != target.ID {
+			removedIDs = append(removedIDs, cat.ID)
+		}
+	}
+
+	if len(removedIDs) > 0 {
+		newParentID := target.ID
+		if target.ParentID != nil {
+			newParentID = *target.ParentID
+			for _, id := range removedIDs {
+				if id == *target.ParentID {
+					// Induk tujuan ikut digabung, tujuan menjadi kategori utama
+					newParentID = target.ID
+					_, err = categoryCollection.UpdateOne(ctx, bson.M{"_id": target.ID}, bson.M{"$unset": bson.M{"parent_id": ""}})
+					if err != nil {
+						return nil, err
+					}
+					break
+				}
+			}
+		}
+
+		_, err = categoryCollection.UpdateMany(ctx,
+			bson.M{"user_id": userID, "parent_id": bson.M{"$in": removedIDs}, "_id": bson.M{"$ne": target.ID}},
+			bson.M{"$set": bson.M{"parent_id": newParentID, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	deleteResult, err := categoryCollection.DeleteMany(ctx, categoryFilter)
+	if err != nil {
+		return nil, err
+	}
+	result.CategoriesRemoved = deleteResult.DeletedCount
+
+	return result, nil
+}
+
+// GetCategories returns the logged in user's categories, or the default
+// expense and income categories for clients without a token
+func GetCategories(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusOK, gin.H{
+			"categories":        models.AllowedCategories,
+			"income_categories": models.DefaultIncomeCategories,
+		})
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	categories, err := getUserCategories(ctx, objectID, "pengeluaran")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	incomeCategories, err := getUserCategories(ctx, objectID, "pemasukan")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories":        categoryNames(categories),
+		"income_categories": categoryNames(incomeCategories),
+		"details":           append(categories, incomeCategories...),
+	})
+}
+
+func CreateCategory(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.CreateCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection := config.GetCollection("categories")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tipe := input.Tipe
+	if tipe == "" {
+		tipe = "pengeluaran"
+	}
+
+	exists, _, err := validateUserCategory(ctx, objectID, tipe, input.Nama)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kategori sudah ada"})
+		return
+	}
+
+	var parentID *primitive.ObjectID
+	if input.ParentID != "" {
+		parent, errMessage := findParentCategory(ctx, objectID, tipe, input.ParentID)
+		if errMessage != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+			return
+		}
+		parentID = &parent.ID
+	}
+
+	category := models.Category{
+		ID:        primitive.NewObjectID(),
+		UserID:    objectID,
+		Nama:      input.Nama,
+		Tipe:      tipe,
+		ParentID:  parentID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	_, err = collection.InsertOne(ctx, category)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Kategori berhasil ditambahkan",
+		"category": category,
+	})
+}
+
+func UpdateCategory(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	categoryID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(categoryID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var input models.UpdateCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection := config.GetCollection("categories")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Check if category exists and belongs to user
+	var existingCategory models.Category
+	err = collection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&existingCategory)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kategori tidak ditemukan"})
+		return
+	}
+
+	// Pindahkan ke induk lain atau jadikan kategori utama
+	if input.ParentID != nil {
+		update := bson.M{"$unset": bson.M{"parent_id": ""}}
+		if *input.ParentID != "" {
+			parent, errMessage := findParentCategory(ctx, userObjectID, existingCategory.Tipe, *input.ParentID)
+			if errMessage != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+				return
+			}
+			if parent.ID == objectID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Kategori tidak dapat menjadi induk dirinya sendiri"})
+				return
+			}
+
+			childCount, err := collection.CountDocuments(ctx, bson.M{"user_id": userObjectID, "parent_id": objectID})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+				return
+			}
+			if childCount > 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Kategori yang memiliki subkategori tidak dapat dijadikan subkategori"})
+				return
+			}
+			update = bson.M{"$set": bson.M{"parent_id": parent.ID}}
+		}
+
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+			return
+		}
+	}
+
+	if input.Nama != "" && input.Nama != existingCategory.Nama {
+		exists, _, err := validateUserCategory(ctx, userObjectID, existingCategory.Tipe, input.Nama)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+			return
+		}
+		if exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Kategori sudah ada, gunakan /api/categories/merge untuk menggabungkan"})
+			return
+		}
+	}
+
+	// Rename kategori sekaligus memperbarui transaksi yang memakai nama lama
+	migration := &CategoryMigrationResult{Mode: "rename"}
+	if input.Nama != "" && input.Nama != existingCategory.Nama {
+		migration, err = migrateCategories(ctx, userObjectID, existingCategory.Tipe, []string{existingCategory.Nama}, input.Nama, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+			return
+		}
+	}
+
+	// Get updated category
+	var category models.Category
+	collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&category)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Kategori berhasil diperbarui",
+		"category":  category,
+		"migration": migration,
+	})
+}
+
+func DeleteCategory(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	categoryID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(categoryID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	collection := config.GetCollection("categories")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var category models.Category
+	err = collection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&category)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kategori tidak ditemukan"})
+		return
+	}
+
+	childCount, err := collection.CountDocuments(ctx, bson.M{"user_id": userObjectID, "parent_id": objectID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+	if childCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "Kategori masih memiliki subkategori",
+			"subcategory_count": childCount,
+		})
+		return
+	}
+
+	// Kategori yang masih dipakai transaksi tidak boleh dihapus
+	count, err := config.GetCollection("transactions").CountDocuments(ctx, bson.M{
+		"user_id": userObjectID,
+		"tipe":    category.Tipe,
+		"$or": []bson.M{
+			{"kategori": category.Nama},
+			{"splits.kategori": category.Nama},
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "Kategori masih digunakan oleh transaksi, gabungkan ke kategori lain melalui /api/categories/merge",
+			"transaction_count": count,
+		})
+		return
+	}
+
+	_, err = collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+
+	// Budget kategori yang dihapus tidak lagi berlaku
+	if category.Tipe == "pengeluaran" {
+		config.GetCollection("budgets").DeleteMany(ctx, bson.M{"user_id": userObjectID, "kategori": category.Nama})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Kategori berhasil dihapus"})
+}
+
+// MergeCategories mengganti nama atau menggabungkan kategori dan memindahkan
+// semua transaksi yang terdampak. Gunakan dry_run untuk melihat jumlah
+// dokumen yang akan berubah tanpa menyimpan apa pun.
+func MergeCategories(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.MergeCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tipe := input.Tipe
+	if tipe == "" {
+		tipe = "pengeluaran"
+	}
+
+	for _, nama := range input.From {
+		if nama == input.To {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Kategori tujuan tidak boleh termasuk kategori asal"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := migrateCategories(ctx, objectID, tipe, input.From, input.To, input.DryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge categories"})
+		return
+	}
+
+	message := "Kategori berhasil digabungkan"
+	if result.Mode == "rename" {
+		message = "Kategori berhasil diganti namanya"
+	}
+	if input.DryRun {
+		message = "Simulasi selesai, tidak ada data yang diubah"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"result":  result,
+	})
+}
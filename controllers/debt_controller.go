@@ -0,0 +1,546 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	errDebtNotFound = errors.New("debt not found")
+	errDebtPaidOff  = errors.New("debt is already paid off")
+	errDebtOverpaid = errors.New("payment exceeds remaining debt")
+)
+
+// parseDueDate memvalidasi jatuh tempo dengan format YYYY-MM-DD. Berbeda
+// dengan deadline goal, jatuh tempo boleh di masa lalu untuk mencatat
+// utang lama yang sudah terlambat.
+func parseDueDate(value string) (*time.Time, string) {
+	dueDate, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, "Format due_date tidak valid. Gunakan format YYYY-MM-DD"
+	}
+	return &dueDate, ""
+}
+
+func CreateDebt(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.CreateDebtInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var dueDate *time.Time
+	if input.DueDate != "" {
+		parsed, errMessage := parseDueDate(input.DueDate)
+		if errMessage != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+			return
+		}
+		dueDate = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	debt := models.Debt{
+		ID:           primitive.NewObjectID(),
+		UserID:       objectID,
+		Counterparty: input.Counterparty,
+		Direction:    input.Direction,
+		Principal:    input.Principal,
+		Paid:         0,
+		Remaining:    input.Principal,
+		DueDate:      dueDate,
+		Catatan:      input.Catatan,
+		Status:       "aktif",
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	_, err = config.GetCollection("debts").InsertOne(ctx, debt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create debt"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Utang/piutang berhasil dicatat",
+		"debt":    debt,
+	})
+}
+
+func GetDebts(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": objectID}
+	if direction := c.Query("direction"); direction == "utang" || direction == "piutang" {
+		filter["direction"] = direction
+	}
+	if status := c.Query("status"); status == "aktif" || status == "lunas" {
+		filter["status"] = status
+	}
+
+	// Yang paling dekat jatuh tempo ditampilkan lebih dulu
+	opts := options.Find().SetSort(bson.D{{Key: "status", Value: 1}, {Key: "due_date", Value: 1}, {Key: "created_at", Value: -1}})
+	cursor, err := config.GetCollection("debts").Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch debts"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	debts := []models.Debt{}
+	if err := cursor.All(ctx, &debts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode debts"})
+		return
+	}
+
+	totalUtang, totalPiutang, err := getDebtTotals(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get debt totals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"debts":         debts,
+		"count":         len(debts),
+		"total_utang":   totalUtang,
+		"total_piutang": totalPiutang,
+	})
+}
+
+func GetDebtByID(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid debt ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var debt models.Debt
+	err = config.GetCollection("debts").FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&debt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utang/piutang tidak ditemukan"})
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "tanggal", Value: -1}, {Key: "created_at", Value: -1}})
+	cursor, err := config.GetCollection("debt_payments").Find(ctx, bson.M{"debt_id": debt.ID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch debt payments"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	payments := []models.DebtPayment{}
+	if err := cursor.All(ctx, &payments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode debt payments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"debt":       debt,
+		"payments":   payments,
+		"is_overdue": debt.IsOverdue(time.Now()),
+	})
+}
+
+func UpdateDebt(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid debt ID"})
+		return
+	}
+
+	var input models.UpdateDebtInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+	if input.Counterparty != "" {
+		set["counterparty"] = input.Counterparty
+	}
+	if input.Catatan != nil {
+		set["catatan"] = *input.Catatan
+	}
+	if input.DueDate != nil {
+		if *input.DueDate == "" {
+			unset["due_date"] = ""
+		} else {
+			dueDate, errMessage := parseDueDate(*input.DueDate)
+			if errMessage != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+				return
+			}
+			set["due_date"] = dueDate
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var debt models.Debt
+	err = config.GetCollection("debts").FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "user_id": userObjectID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&debt)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utang/piutang tidak ditemukan"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update debt"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Utang/piutang berhasil diperbarui",
+		"debt":    debt,
+	})
+}
+
+func DeleteDebt(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid debt ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.GetCollection("debts").DeleteOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete debt"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utang/piutang tidak ditemukan"})
+		return
+	}
+
+	// Riwayat pembayaran ikut dihapus, transaksinya tetap disimpan tanpa tautan
+	config.GetCollection("debt_payments").DeleteMany(ctx, bson.M{"debt_id": objectID})
+	config.GetCollection("transactions").UpdateMany(ctx,
+		bson.M{"debt_id": objectID},
+		bson.M{"$unset": bson.M{"debt_id": ""}},
+	)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Utang/piutang berhasil dihapus"})
+}
+
+// AddDebtPayment - Mencatat pembayaran utang atau penerimaan piutang
+func AddDebtPayment(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid debt ID"})
+		return
+	}
+
+	var input models.DebtPaymentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tanggal := today(time.Now())
+	if input.Tanggal != "" {
+		tanggal, err = time.Parse("2006-01-02", input.Tanggal)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format tanggal tidak valid"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var accountID *primitive.ObjectID
+	if input.RecordTransaction && input.AccountID != "" {
+		account, err := findUserAccount(ctx, userObjectID, input.AccountID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak ditemukan"})
+			return
+		}
+		accountID = &account.ID
+	}
+
+	payment := debtPayment{
+		UserID:            userObjectID,
+		DebtID:            objectID,
+		Amount:            input.Amount,
+		Tanggal:           tanggal,
+		Catatan:           input.Catatan,
+		RecordTransaction: input.RecordTransaction,
+		AccountID:         accountID,
+	}
+
+	result, err := payDebt(ctx, payment)
+	if err == errDebtNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Utang/piutang tidak ditemukan"})
+		return
+	}
+	if err == errDebtPaidOff {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Utang/piutang sudah lunas"})
+		return
+	}
+	if err == errDebtOverpaid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pembayaran melebihi sisa utang/piutang"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record debt payment"})
+		return
+	}
+
+	response := gin.H{
+		"message": "Pembayaran berhasil dicatat",
+		"debt":    result.Debt,
+		"payment": result.Payment,
+	}
+	if result.Transaction != nil {
+		response["transaction"] = result.Transaction
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// debtPayment adalah satu pembayaran yang akan diterapkan ke utang/piutang
+type debtPayment struct {
+	UserID            primitive.ObjectID
+	DebtID            primitive.ObjectID
+	Amount            models.Money
+	Tanggal           time.Time
+	Catatan           string
+	RecordTransaction bool
+	AccountID         *primitive.ObjectID
+}
+
+type debtPaymentResult struct {
+	Debt        models.Debt
+	Payment     *models.DebtPayment
+	Transaction *models.Transaction
+}
+
+// payDebt mengurangi sisa utang/piutang dengan satu update bersyarat agar
+// pembayaran bersamaan tidak dapat melebihi sisa. Jika transaksi ikut
+// dicatat, semuanya ditulis dalam satu transaksi MongoDB.
+func payDebt(ctx context.Context, p debtPayment) (*debtPaymentResult, error) {
+	if !p.RecordTransaction {
+		return applyDebtPayment(ctx, p)
+	}
+
+	session, err := config.DB.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	result, err := session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return applyDebtPayment(sc, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*debtPaymentResult), nil
+}
+
+func applyDebtPayment(ctx context.Context, p debtPayment) (*debtPaymentResult, error) {
+	now := time.Now()
+	remaining := bson.M{"$subtract": bson.A{toMinorUnits("$remaining"), p.Amount}}
+	paidOff := bson.M{"$lte": bson.A{remaining, 0}}
+
+	var result debtPaymentResult
+	err := config.GetCollection("debts").FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":       p.DebtID,
+			"user_id":   p.UserID,
+			"status":    "aktif",
+			"remaining": bson.M{"$gte": p.Amount},
+		},
+		bson.A{bson.M{"$set": bson.M{
+			"paid":        bson.M{"$add": bson.A{toMinorUnits("$paid"), p.Amount}},
+			"remaining":   remaining,
+			"status":      bson.M{"$cond": bson.A{paidOff, "lunas", "aktif"}},
+			"paid_off_at": bson.M{"$cond": bson.A{paidOff, now, "$$REMOVE"}},
+			"updated_at":  now,
+		}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&result.Debt)
+	if err == mongo.ErrNoDocuments {
+		return nil, debtPaymentRejection(ctx, p)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payment := models.DebtPayment{
+		ID:        primitive.NewObjectID(),
+		UserID:    p.UserID,
+		DebtID:    p.DebtID,
+		Amount:    p.Amount,
+		Catatan:   p.Catatan,
+		Tanggal:   p.Tanggal,
+		CreatedAt: now,
+	}
+
+	if p.RecordTransaction {
+		transaction, err := recordDebtTransaction(ctx, result.Debt, p)
+		if err != nil {
+			return nil, err
+		}
+		payment.TransactionID = &transaction.ID
+		result.Transaction = transaction
+	}
+
+	if _, err := config.GetCollection("debt_payments").InsertOne(ctx, payment); err != nil {
+		return nil, err
+	}
+	result.Payment = &payment
+
+	return &result, nil
+}
+
+// debtPaymentRejection menjelaskan mengapa update bersyarat tidak mengenai dokumen
+func debtPaymentRejection(ctx context.Context, p debtPayment) error {
+	var debt models.Debt
+	err := config.GetCollection("debts").FindOne(ctx, bson.M{"_id": p.DebtID, "user_id": p.UserID}).Decode(&debt)
+	if err == mongo.ErrNoDocuments {
+		return errDebtNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if debt.Status == "lunas" {
+		return errDebtPaidOff
+	}
+	return errDebtOverpaid
+}
+
+// recordDebtTransaction mencatat pembayaran utang sebagai pengeluaran atau
+// penerimaan piutang sebagai pemasukan kategori Utang Piutang
+func recordDebtTransaction(ctx context.Context, debt models.Debt, p debtPayment) (*models.Transaction, error) {
+	catatan := p.Catatan
+	if catatan == "" {
+		if debt.Direction == "piutang" {
+			catatan = "Pembayaran piutang dari " + debt.Counterparty
+		} else {
+			catatan = "Pembayaran utang ke " + debt.Counterparty
+		}
+	}
+
+	currency, err := accountCurrency(ctx, p.UserID, p.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureCategory(ctx, p.UserID, debt.TransactionTipe(), models.DebtCategory); err != nil {
+		return nil, err
+	}
+
+	transaction := models.Transaction{
+		ID:        primitive.NewObjectID(),
+		UserID:    p.UserID,
+		AccountID: p.AccountID,
+		Tipe:      debt.TransactionTipe(),
+		Nominal:   p.Amount,
+		Currency:  currency,
+		Kategori:  models.DebtCategory,
+		Catatan:   catatan,
+		Tanggal:   p.Tanggal,
+		DebtID:    &debt.ID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	_, err = config.GetCollection("transactions").InsertOne(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// getDebtTotals menjumlahkan sisa utang (user berutang) dan sisa piutang
+// (user dipinjami) yang belum lunas
+func getDebtTotals(ctx context.Context, userID primitive.ObjectID) (models.Money, models.Money, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"user_id": userID, "status": "aktif"}},
+		{"$group": bson.M{
+			"_id":   "$direction",
+			"total": sumMoney("$remaining"),
+		}},
+	}
+
+	cursor, err := config.GetCollection("debts").Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Direction string       `bson:"_id"`
+		Total     models.Money `bson:"total"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, 0, err
+	}
+
+	var totalUtang, totalPiutang models.Money
+	for _, r := range results {
+		if r.Direction == "utang" {
+			totalUtang = r.Total
+		} else if r.Direction == "piutang" {
+			totalPiutang = r.Total
+		}
+	}
+	return totalUtang, totalPiutang, nil
+}
@@ -0,0 +1,357 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureExchangeRateIndexes membuat unique index (user_id, from, to, tanggal)
+// sehingga satu pasangan mata uang hanya memiliki satu kurs per tanggal
+func EnsureExchangeRateIndexes(ctx context.Context) error {
+	_, err := config.GetCollection("exchange_rates").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "user_id", Value: 1},
+			{Key: "from", Value: 1},
+			{Key: "to", Value: 1},
+			{Key: "tanggal", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// getBaseCurrency returns the base currency of the user, IDR by default
+func getBaseCurrency(ctx context.Context, userID primitive.ObjectID) (string, error) {
+	var user models.User
+	err := config.GetCollection("users").FindOne(ctx, bson.M{"_id": userID},
+		options.FindOne().SetProjection(bson.M{"base_currency": 1}),
+	).Decode(&user)
+	if err != nil {
+		return "", err
+	}
+	return user.Currency(), nil
+}
+
+// accountCurrency returns the currency of a transaction created on accountID:
+// the currency of the account, or the base currency of the user when the
+// transaction has no account or the account has no currency
+func accountCurrency(ctx context.Context, userID primitive.ObjectID, accountID *primitive.ObjectID) (string, error) {
+	if accountID != nil {
+		var account models.Account
+		err := config.GetCollection("accounts").FindOne(ctx, bson.M{"_id": *accountID},
+			options.FindOne().SetProjection(bson.M{"currency": 1}),
+		).Decode(&account)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return "", err
+		}
+		if account.Currency != "" {
+			return account.Currency, nil
+		}
+	}
+	return getBaseCurrency(ctx, userID)
+}
+
+// rateTable mengonversi nominal dalam mata uang lain ke mata uang dasar user
+// memakai kurs terakhir yang berlaku pada tanggal transaksi. Mata uang yang
+// kursnya belum diisi dicatat di missing dan nominalnya tidak dihitung.
+type rateTable struct {
+	base    string
+	rates   []models.ExchangeRate // urut dari tanggal terbaru
+	missing map[string]bool
+}
+
+func loadRateTable(ctx context.Context, userID primitive.ObjectID) (*rateTable, error) {
+	base, err := getBaseCurrency(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "tanggal", Value: -1}})
+	cursor, err := config.GetCollection("exchange_rates").Find(ctx, bson.M{
+		"user_id": userID,
+		"$or":     []bson.M{{"to": base}, {"from": base}},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &rateTable{base: base, missing: map[string]bool{}}
+	if err := cursor.All(ctx, &table.rates); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// rate returns the value of 1 currency in the base currency on tanggal.
+// A rate stored in the opposite direction (base to currency) is inverted.
+func (t *rateTable) rate(currency string, tanggal time.Time) (float64, bool) {
+	for _, r := range t.rates {
+		if r.Tanggal.After(tanggal) {
+			continue
+		}
+		if r.From == currency && r.To == t.base {
+			return r.Rate, true
+		}
+		if r.From == t.base && r.To == currency {
+			return 1 / r.Rate, true
+		}
+	}
+	return 0, false
+}
+
+// convert returns amount in the base currency. Transaksi tanpa mata uang
+// sudah dalam mata uang dasar.
+func (t *rateTable) convert(amount models.Money, currency string, tanggal time.Time) models.Money {
+	if currency == "" || currency == t.base {
+		return amount
+	}
+	rate, ok := t.rate(currency, tanggal)
+	if !ok {
+		t.missing[currency] = true
+		return 0
+	}
+	return amount.MulRatio(rate)
+}
+
+// missingRates returns the currencies that could not be converted
+func (t *rateTable) missingRates() []string {
+	currencies := []string{}
+	for currency := range t.missing {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// withCurrencyKey menambahkan mata uang dan tanggal transaksi ke _id $group
+// agar nominal bisa dikonversi dengan kurs pada tanggalnya. Nominal dalam
+// mata uang dasar tidak perlu dipisah per tanggal.
+func withCurrencyKey(id bson.M, base string) bson.M {
+	id["currency"] = "$currency"
+	id["tanggal"] = bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$currency", base}}, base}},
+		nil,
+		"$tanggal",
+	}}
+	return id
+}
+
+// currencyGroup adalah field _id tambahan dari withCurrencyKey
+type currencyGroup struct {
+	Currency string    `bson:"currency"`
+	Tanggal  time.Time `bson:"tanggal"`
+}
+
+func CreateExchangeRate(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.CreateExchangeRateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tanggal := today(time.Now())
+	if input.Tanggal != "" {
+		tanggal, err = time.Parse("2006-01-02", input.Tanggal)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format tanggal tidak valid. Gunakan format YYYY-MM-DD"})
+			return
+		}
+	}
+
+	collection := config.GetCollection("exchange_rates")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if input.To == "" {
+		input.To, err = getBaseCurrency(ctx, objectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch base currency"})
+			return
+		}
+	}
+	if input.From == input.To {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Mata uang asal dan tujuan tidak boleh sama"})
+		return
+	}
+
+	rate := models.ExchangeRate{
+		ID:        primitive.NewObjectID(),
+		UserID:    objectID,
+		From:      input.From,
+		To:        input.To,
+		Rate:      input.Rate,
+		Tanggal:   tanggal,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	_, err = collection.InsertOne(ctx, rate)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Kurs untuk mata uang dan tanggal ini sudah ada"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exchange rate"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":       "Kurs berhasil ditambahkan",
+		"exchange_rate": rate,
+	})
+}
+
+func GetExchangeRates(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	collection := config.GetCollection("exchange_rates")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Filter by pasangan mata uang if provided
+	filter := bson.M{"user_id": objectID}
+	if from := c.Query("from"); from != "" {
+		filter["from"] = from
+	}
+	if to := c.Query("to"); to != "" {
+		filter["to"] = to
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "tanggal", Value: -1}, {Key: "from", Value: 1}})
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	rates := []models.ExchangeRate{}
+	if err := cursor.All(ctx, &rates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode exchange rates"})
+		return
+	}
+
+	base, err := getBaseCurrency(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch base currency"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"exchange_rates": rates,
+		"count":          len(rates),
+		"base_currency":  base,
+	})
+}
+
+func UpdateExchangeRate(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	rateID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(rateID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exchange rate ID"})
+		return
+	}
+
+	var input models.UpdateExchangeRateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := bson.M{"updated_at": time.Now()}
+	if input.Rate > 0 {
+		update["rate"] = input.Rate
+	}
+	if input.Tanggal != "" {
+		tanggal, err := time.Parse("2006-01-02", input.Tanggal)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format tanggal tidak valid"})
+			return
+		}
+		update["tanggal"] = tanggal
+	}
+
+	collection := config.GetCollection("exchange_rates")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}, bson.M{"$set": update})
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Kurs untuk mata uang dan tanggal ini sudah ada"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update exchange rate"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kurs tidak ditemukan"})
+		return
+	}
+
+	// Get updated exchange rate
+	var rate models.ExchangeRate
+	collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&rate)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Kurs berhasil diperbarui",
+		"exchange_rate": rate,
+	})
+}
+
+func DeleteExchangeRate(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	rateID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(rateID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exchange rate ID"})
+		return
+	}
+
+	collection := config.GetCollection("exchange_rates")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exchange rate"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kurs tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Kurs berhasil dihapus"})
+}
@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// goalRecentNet menjumlahkan setoran dikurangi penarikan tiap goal sejak
+// models.GoalRateWindowStart(now), untuk menghitung laju tabungan pada insight
+func goalRecentNet(ctx context.Context, goalIDs []primitive.ObjectID, now time.Time) (map[primitive.ObjectID]models.Money, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"goal_id":    bson.M{"$in": goalIDs},
+			"created_at": bson.M{"$gte": models.GoalRateWindowStart(now)},
+		}},
+		{"$group": bson.M{
+			"_id": "$goal_id",
+			"net": bson.M{"$sum": bson.M{
+				"$cond": bson.A{
+					bson.M{"$eq": bson.A{"$direction", "tarik"}},
+					bson.M{"$multiply": bson.A{toMinorUnits("$amount"), -1}},
+					toMinorUnits("$amount"),
+				},
+			}},
+		}},
+	}
+
+	cursor, err := config.GetCollection("goal_contributions").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		GoalID primitive.ObjectID `bson:"_id"`
+		Net    models.Money       `bson:"net"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	net := make(map[primitive.ObjectID]models.Money, len(results))
+	for _, r := range results {
+		net[r.GoalID] = r.Net
+	}
+	return net, nil
+}
+
+// goalInsight menghitung insight goal dari riwayat kontribusinya. Insight
+// hanya pelengkap response, jadi kegagalan membaca riwayat dianggap belum ada setoran.
+func goalInsight(ctx context.Context, goal models.FinancialGoal) models.GoalInsight {
+	now := time.Now()
+	net, _ := goalRecentNet(ctx, []primitive.ObjectID{goal.ID}, now)
+	return goal.Insight(now, net[goal.ID])
+}
+
+// recordGoalContribution menulis satu entri riwayat setoran ("setor") atau
+// penarikan ("tarik") goal
+func recordGoalContribution(ctx context.Context, goal models.FinancialGoal, m goalFundsMovement, transactionID *primitive.ObjectID) (*models.GoalContribution, error) {
+	contribution := models.GoalContribution{
+		ID:            primitive.NewObjectID(),
+		UserID:        m.UserID,
+		GoalID:        goal.ID,
+		Amount:        m.Amount,
+		Direction:     m.Direction,
+		Catatan:       m.Catatan,
+		TransactionID: transactionID,
+		RuleID:        m.RuleID,
+		SourceID:      m.SourceID,
+		CreatedAt:     time.Now(),
+	}
+
+	_, err := config.GetCollection("goal_contributions").InsertOne(ctx, contribution)
+	if err != nil {
+		return nil, err
+	}
+	return &contribution, nil
+}
+
+// findUserGoal mengambil goal dari parameter :id yang dapat diakses user
+// yang sedang login dengan peran minimal minRole
+func findUserGoal(ctx context.Context, c *gin.Context, minRole string) (*models.FinancialGoal, bool) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goal ID"})
+		return nil, false
+	}
+
+	if err := refreshGoalStatuses(ctx, userObjectID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh goal status"})
+		return nil, false
+	}
+
+	var goal models.FinancialGoal
+	err = config.GetCollection("financial_goals").FindOne(ctx, withAccess(bson.M{"_id": objectID}, userObjectID, "viewer")).Decode(&goal)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Goal tidak ditemukan"})
+		return nil, false
+	}
+	if !hasRole(goal.RoleOf(userObjectID), minRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki akses untuk aksi ini pada goal"})
+		return nil, false
+	}
+	return &goal, true
+}
+
+// GetGoalHistory - Riwayat setoran dan penarikan goal, terbaru lebih dulu
+func GetGoalHistory(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	goal, ok := findUserGoal(ctx, c, "viewer")
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page harus berupa angka minimal 1"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit harus berupa angka antara 1 dan 100"})
+		return
+	}
+
+	collection := config.GetCollection("goal_contributions")
+	filter := bson.M{"goal_id": goal.ID}
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count goal history"})
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch goal history"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	history := []models.GoalContribution{}
+	if err := cursor.All(ctx, &history); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode goal history"})
+		return
+	}
+
+	totalPages := (int(total) + limit - 1) / limit
+
+	c.JSON(http.StatusOK, gin.H{
+		"history":     history,
+		"count":       len(history),
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+		"total_pages": totalPages,
+	})
+}
+
+// GetGoalMonthlySavings - Total setoran dan penarikan goal per bulan untuk grafik
+func GetGoalMonthlySavings(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	goal, ok := findUserGoal(ctx, c, "viewer")
+	if !ok {
+		return
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"goal_id": goal.ID}},
+		{"$group": bson.M{
+			"_id": bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$created_at"}},
+			"setor": bson.M{"$sum": bson.M{
+				"$cond": bson.A{bson.M{"$eq": bson.A{"$direction", "setor"}}, toMinorUnits("$amount"), 0},
+			}},
+			"tarik": bson.M{"$sum": bson.M{
+				"$cond": bson.A{bson.M{"$eq": bson.A{"$direction", "tarik"}}, toMinorUnits("$amount"), 0},
+			}},
+		}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+
+	cursor, err := config.GetCollection("goal_contributions").Aggregate(ctx, pipeline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get monthly savings"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	months := []models.GoalMonthlySaving{}
+	if err := cursor.All(ctx, &months); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode monthly savings"})
+		return
+	}
+
+	var cumulative models.Money
+	for i := range months {
+		months[i].Net = months[i].Setor - months[i].Tarik
+		cumulative += months[i].Net
+		months[i].Cumulative = cumulative
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"goal_id":        goal.ID,
+		"data":           months,
+		"current_amount": goal.CurrentAmount,
+	})
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -28,6 +30,16 @@ func CreateGoal(c *gin.Context) {
 		return
 	}
 
+	var deadline *time.Time
+	if input.Deadline != "" {
+		parsed, errMessage := parseGoalDeadline(input.Deadline)
+		if errMessage != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+			return
+		}
+		deadline = parsed
+	}
+
 	collection := config.GetCollection("financial_goals")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -38,6 +50,8 @@ func CreateGoal(c *gin.Context) {
 		Nama:          input.Nama,
 		TargetAmount:  input.TargetAmount,
 		CurrentAmount: 0,
+		Deadline:      deadline,
+		Status:        "active",
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
@@ -49,12 +63,25 @@ func CreateGoal(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Goal berhasil dibuat",
-		"goal":    goal,
+		"message":             "Goal berhasil dibuat",
+		"goal":                goal,
 		"progress_percentage": goal.GetProgressPercentage(),
+		"insight":             goal.Insight(time.Now(), 0),
 	})
 }
 
+// parseGoalDeadline memvalidasi deadline goal dengan format YYYY-MM-DD
+func parseGoalDeadline(value string) (*time.Time, string) {
+	deadline, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, "Format deadline tidak valid. Gunakan format YYYY-MM-DD"
+	}
+	if deadline.Before(today(time.Now())) {
+		return nil, "Deadline tidak boleh di masa lalu"
+	}
+	return &deadline, ""
+}
+
 func GetGoals(c *gin.Context) {
 	userID, _ := c.Get("userID")
 	objectID, err := primitive.ObjectIDFromHex(userID.(string))
@@ -67,8 +94,22 @@ func GetGoals(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if err := refreshGoalStatuses(ctx, objectID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh goal status"})
+		return
+	}
+
+	// Goal yang diarsipkan hanya ditampilkan jika diminta
+	filter := withAccess(bson.M{"status": bson.M{"$ne": "archived"}}, objectID, "viewer")
+	status := c.Query("status")
+	if status != "" && (status == "active" || status == "completed" || status == "archived" || status == "locked") {
+		filter["status"] = status
+	} else if c.Query("include_archived") == "true" {
+		delete(filter, "status")
+	}
+
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
-	cursor, err := collection.Find(ctx, bson.M{"user_id": objectID}, opts)
+	cursor, err := collection.Find(ctx, filter, opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch goals"})
 		return
@@ -84,14 +125,24 @@ func GetGoals(c *gin.Context) {
 	// Add progress percentage to each goal
 	type GoalWithProgress struct {
 		models.FinancialGoal
-		ProgressPercentage float64 `json:"progress_percentage"`
+		ProgressPercentage float64            `json:"progress_percentage"`
+		Insight            models.GoalInsight `json:"insight"`
 	}
 
+	// Laju tabungan semua goal diambil sekaligus dari riwayat kontribusi
+	now := time.Now()
+	goalIDs := make([]primitive.ObjectID, len(goals))
+	for i, g := range goals {
+		goalIDs[i] = g.ID
+	}
+	recentNet, _ := goalRecentNet(ctx, goalIDs, now)
+
 	var goalsWithProgress []GoalWithProgress
 	for _, g := range goals {
 		goalsWithProgress = append(goalsWithProgress, GoalWithProgress{
 			FinancialGoal:      g,
 			ProgressPercentage: g.GetProgressPercentage(),
+			Insight:            g.Insight(now, recentNet[g.ID]),
 		})
 	}
 
@@ -116,16 +167,38 @@ func GetGoalByID(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if err := refreshGoalStatuses(ctx, userObjectID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh goal status"})
+		return
+	}
+
 	var goal models.FinancialGoal
-	err = collection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&goal)
+	err = collection.FindOne(ctx, withAccess(bson.M{"_id": objectID}, userObjectID, "viewer")).Decode(&goal)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Goal tidak ditemukan"})
 		return
 	}
 
+	// Transaksi setoran dan penarikan yang terhubung dengan goal ini, termasuk milik anggota lain
+	transactions := []models.Transaction{}
+	opts := options.Find().SetSort(bson.D{{Key: "tanggal", Value: -1}})
+	cursor, err := config.GetCollection("transactions").Find(ctx, bson.M{"goal_id": objectID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch goal transactions"})
+		return
+	}
+	defer cursor.Close(ctx)
+	if err := cursor.All(ctx, &transactions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode goal transactions"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"goal":                goal,
 		"progress_percentage": goal.GetProgressPercentage(),
+		"insight":             goalInsight(ctx, goal),
+		"role":                goal.RoleOf(userObjectID),
+		"transactions":        transactions,
 	})
 }
 
@@ -150,11 +223,8 @@ func UpdateGoal(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Check if goal exists and belongs to user
-	var existingGoal models.FinancialGoal
-	err = collection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&existingGoal)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Goal tidak ditemukan"})
+	// Check if goal exists and user may edit it
+	if _, ok := findUserGoal(ctx, c, "editor"); !ok {
 		return
 	}
 
@@ -166,20 +236,42 @@ func UpdateGoal(c *gin.Context) {
 		update["target_amount"] = input.TargetAmount
 	}
 
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": update})
+	changes := bson.M{"$set": update}
+	if input.Deadline != nil {
+		if *input.Deadline == "" {
+			changes["$unset"] = bson.M{"deadline": ""}
+		} else {
+			deadline, errMessage := parseGoalDeadline(*input.Deadline)
+			if errMessage != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": errMessage})
+				return
+			}
+			update["deadline"] = deadline
+		}
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, changes)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update goal"})
 		return
 	}
 
-	// Get updated goal
-	var goal models.FinancialGoal
-	collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&goal)
+	// Perubahan target dapat menyelesaikan goal atau membukanya kembali
+	if err := refreshGoalStatuses(ctx, userObjectID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh goal status"})
+		return
+	}
+	goal, err := syncGoalCompletion(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update goal status"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":             "Goal berhasil diperbarui",
 		"goal":                goal,
 		"progress_percentage": goal.GetProgressPercentage(),
+		"insight":             goalInsight(ctx, *goal),
 	})
 }
 
@@ -200,38 +292,253 @@ func AddProgressToGoal(c *gin.Context) {
 		return
 	}
 
-	collection := config.GetCollection("financial_goals")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Check if goal exists and belongs to user
-	var goal models.FinancialGoal
-	err = collection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&goal)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Goal tidak ditemukan"})
-		return
+	var accountID *primitive.ObjectID
+	if input.RecordTransaction && input.AccountID != "" {
+		account, err := findUserAccount(ctx, userObjectID, input.AccountID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak ditemukan"})
+			return
+		}
+		accountID = &account.ID
 	}
 
-	newAmount := goal.CurrentAmount + input.Amount
-
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
-		"$set": bson.M{
-			"current_amount": newAmount,
-			"updated_at":     time.Now(),
-		},
+	movement, err := moveGoalFunds(ctx, goalFundsMovement{
+		UserID:            userObjectID,
+		GoalID:            objectID,
+		Direction:         "setor",
+		Amount:            input.Amount,
+		RecordTransaction: input.RecordTransaction,
+		AccountID:         accountID,
+		Catatan:           input.Catatan,
 	})
+	if err == errGoalNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Goal tidak ditemukan"})
+		return
+	}
+	if err == errGoalForbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki akses untuk aksi ini pada goal"})
+		return
+	}
+	if err == errGoalArchived {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Goal sudah diarsipkan, kembalikan goal terlebih dahulu"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add progress"})
 		return
 	}
 
-	goal.CurrentAmount = newAmount
-
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message":             "Tabungan berhasil ditambahkan",
-		"goal":                goal,
-		"progress_percentage": goal.GetProgressPercentage(),
+		"goal":                movement.Goal,
+		"progress_percentage": movement.Goal.GetProgressPercentage(),
+		"insight":             goalInsight(ctx, movement.Goal),
+		"contribution":        movement.Contribution,
+	}
+	if movement.Transaction != nil {
+		response["transaction"] = movement.Transaction
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+var (
+	errGoalNotFound          = errors.New("goal not found")
+	errInsufficientGoalFunds = errors.New("insufficient goal funds")
+	errGoalLocked            = errors.New("goal is locked")
+	errGoalArchived          = errors.New("goal is archived")
+	errGoalForbidden         = errors.New("goal is read-only for this user")
+)
+
+// goalFundsMovement adalah satu setoran ("setor") atau penarikan ("tarik") dana goal
+type goalFundsMovement struct {
+	UserID            primitive.ObjectID
+	GoalID            primitive.ObjectID
+	Direction         string
+	Amount            models.Money
+	RecordTransaction bool
+	AccountID         *primitive.ObjectID
+	Catatan           string
+	RuleID            *primitive.ObjectID // diisi jika setoran berasal dari aturan otomatis
+	SourceID          *primitive.ObjectID // transaksi yang memicu aturan otomatis
+}
+
+type goalFundsResult struct {
+	Goal         models.FinancialGoal
+	Transaction  *models.Transaction
+	Contribution *models.GoalContribution
+}
+
+// moveGoalFunds mengubah current_amount goal dengan satu update $inc
+// bersyarat, sehingga penarikan yang berjalan bersamaan tidak dapat membuat
+// saldo goal negatif dan tidak ada update yang hilang. Jika transaksi Goals
+// ikut dicatat, update goal, transaksi dan riwayat ditulis dalam satu
+// transaksi MongoDB agar tidak ada yang tercatat sebagian.
+func moveGoalFunds(ctx context.Context, m goalFundsMovement) (*goalFundsResult, error) {
+	if err := refreshGoalStatuses(ctx, m.UserID); err != nil {
+		return nil, err
+	}
+
+	if !m.RecordTransaction {
+		return applyGoalFunds(ctx, m)
+	}
+
+	session, err := config.DB.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	result, err := session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return applyGoalFunds(sc, m)
 	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*goalFundsResult), nil
+}
+
+func applyGoalFunds(ctx context.Context, m goalFundsMovement) (*goalFundsResult, error) {
+	filter := withAccess(bson.M{"_id": m.GoalID}, m.UserID, "editor")
+	delta := m.Amount
+	if m.Direction == "tarik" {
+		// Hanya berhasil jika saldo goal masih cukup dan goal tidak sedang
+		// terkunci saat update dijalankan
+		filter["current_amount"] = bson.M{"$gte": m.Amount}
+		filter["$or"] = bson.A{
+			bson.M{"status": bson.M{"$ne": "locked"}},
+			bson.M{"locked_until": bson.M{"$lte": time.Now()}},
+		}
+		delta = -m.Amount
+	} else {
+		filter["status"] = bson.M{"$ne": "archived"}
+	}
+
+	var result goalFundsResult
+	err := config.GetCollection("financial_goals").FindOneAndUpdate(ctx, filter,
+		bson.M{
+			"$inc": bson.M{"current_amount": delta},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&result.Goal)
+	if err == mongo.ErrNoDocuments {
+		return nil, goalFundsRejection(ctx, m)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Setoran dapat menyelesaikan goal, penarikan di bawah target membukanya kembali
+	goal, err := syncGoalCompletion(ctx, m.GoalID)
+	if err != nil {
+		return nil, err
+	}
+	result.Goal = *goal
+
+	var transactionID *primitive.ObjectID
+	if m.RecordTransaction {
+		tipe := "pengeluaran"
+		if m.Direction == "tarik" {
+			tipe = "pemasukan"
+		}
+		transaction, err := recordGoalTransaction(ctx, m.UserID, result.Goal, tipe, m.Amount, m.AccountID, m.Catatan)
+		if err != nil {
+			return nil, err
+		}
+		transactionID = &transaction.ID
+		result.Transaction = transaction
+	}
+
+	contribution, err := recordGoalContribution(ctx, result.Goal, m, transactionID)
+	if err != nil {
+		if !m.RecordTransaction {
+			// Tanpa transaksi MongoDB, perubahan saldo dibatalkan manual agar
+			// saldo goal tetap sama dengan riwayatnya
+			undoGoalFunds(ctx, m.GoalID, delta)
+		}
+		return nil, err
+	}
+	result.Contribution = contribution
+
+	return &result, nil
+}
+
+// undoGoalFunds membatalkan $inc dari applyGoalFunds yang riwayatnya gagal dicatat
+func undoGoalFunds(ctx context.Context, goalID primitive.ObjectID, delta models.Money) {
+	config.GetCollection("financial_goals").UpdateOne(ctx,
+		bson.M{"_id": goalID},
+		bson.M{
+			"$inc": bson.M{"current_amount": -delta},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	syncGoalCompletion(ctx, goalID)
+}
+
+// goalFundsRejection menentukan alasan update goal bersyarat tidak berhasil
+func goalFundsRejection(ctx context.Context, m goalFundsMovement) error {
+	var goal models.FinancialGoal
+	err := config.GetCollection("financial_goals").FindOne(ctx, withAccess(bson.M{"_id": m.GoalID}, m.UserID, "viewer")).Decode(&goal)
+	if err == mongo.ErrNoDocuments {
+		return errGoalNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if !hasRole(goal.RoleOf(m.UserID), "editor") {
+		return errGoalForbidden
+	}
+
+	if m.Direction == "setor" {
+		return errGoalArchived
+	}
+	if goal.IsLocked(time.Now()) {
+		return errGoalLocked
+	}
+	return errInsufficientGoalFunds
+}
+
+// recordGoalTransaction mencatat setoran (pengeluaran) atau penarikan
+// (pemasukan) goal sebagai transaksi kategori Goals milik userID yang terhubung ke goal
+func recordGoalTransaction(ctx context.Context, userID primitive.ObjectID, goal models.FinancialGoal, tipe string, amount models.Money, accountID *primitive.ObjectID, catatan string) (*models.Transaction, error) {
+	if catatan == "" {
+		if tipe == "pengeluaran" {
+			catatan = "Tabungan untuk goal " + goal.Nama
+		} else {
+			catatan = "Penarikan dari goal " + goal.Nama
+		}
+	}
+
+	currency, err := accountCurrency(ctx, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := models.Transaction{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		AccountID: accountID,
+		Tipe:      tipe,
+		Nominal:   amount,
+		Currency:  currency,
+		Kategori:  models.GoalCategory,
+		Catatan:   catatan,
+		Tanggal:   today(time.Now()),
+		GoalID:    &goal.ID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	_, err = config.GetCollection("transactions").InsertOne(ctx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
 }
 
 func DeleteGoal(c *gin.Context) {
@@ -260,6 +567,16 @@ func DeleteGoal(c *gin.Context) {
 		return
 	}
 
+	config.GetCollection("goal_contributions").DeleteMany(ctx, bson.M{"goal_id": objectID})
+	config.GetCollection("goal_rules").DeleteMany(ctx, bson.M{"goal_id": objectID})
+	config.GetCollection("invitations").DeleteMany(ctx, bson.M{"resource_type": "goal", "resource_id": objectID, "status": "pending"})
+
+	// Transaksi yang pernah tercatat (termasuk milik anggota) tetap disimpan, hanya tautannya yang dilepas
+	config.GetCollection("transactions").UpdateMany(ctx,
+		bson.M{"goal_id": objectID},
+		bson.M{"$unset": bson.M{"goal_id": ""}},
+	)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Goal berhasil dihapus"})
 }
 
@@ -281,47 +598,72 @@ func WithdrawFromGoal(c *gin.Context) {
 		return
 	}
 
-	collection := config.GetCollection("financial_goals")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Check if goal exists and belongs to user
-	var goal models.FinancialGoal
-	err = collection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&goal)
-	if err != nil {
+	var accountID *primitive.ObjectID
+	if input.RecordTransaction && input.AccountID != "" {
+		account, err := findUserAccount(ctx, userObjectID, input.AccountID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak ditemukan"})
+			return
+		}
+		accountID = &account.ID
+	}
+
+	movement, err := moveGoalFunds(ctx, goalFundsMovement{
+		UserID:            userObjectID,
+		GoalID:            objectID,
+		Direction:         "tarik",
+		Amount:            input.Amount,
+		RecordTransaction: input.RecordTransaction,
+		AccountID:         accountID,
+		Catatan:           input.Catatan,
+	})
+	if err == errGoalNotFound {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Goal tidak ditemukan"})
 		return
 	}
-
-	// Check if withdrawal amount is valid
-	if input.Amount > goal.CurrentAmount {
+	if err == errGoalForbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Anda tidak memiliki akses untuk aksi ini pada goal"})
+		return
+	}
+	if err == errGoalLocked {
+		var goal models.FinancialGoal
+		config.GetCollection("financial_goals").FindOne(ctx, withAccess(bson.M{"_id": objectID}, userObjectID, "viewer")).Decode(&goal)
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":        "Goal terkunci, dana belum dapat ditarik",
+			"locked_until": goal.LockedUntil,
+		})
+		return
+	}
+	if err == errInsufficientGoalFunds {
+		// Saldo goal tidak mencukupi saat update dijalankan
+		var goal models.FinancialGoal
+		config.GetCollection("financial_goals").FindOne(ctx, withAccess(bson.M{"_id": objectID}, userObjectID, "viewer")).Decode(&goal)
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":             "Jumlah penarikan melebihi saldo yang tersedia",
-			"current_amount":    goal.CurrentAmount,
-			"requested_amount":  input.Amount,
+			"error":            "Jumlah penarikan melebihi saldo yang tersedia",
+			"current_amount":   goal.CurrentAmount,
+			"requested_amount": input.Amount,
 		})
 		return
 	}
-
-	newAmount := goal.CurrentAmount - input.Amount
-
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
-		"$set": bson.M{
-			"current_amount": newAmount,
-			"updated_at":     time.Now(),
-		},
-	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw from goal"})
 		return
 	}
 
-	goal.CurrentAmount = newAmount
-
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message":             "Penarikan berhasil",
 		"withdrawn_amount":    input.Amount,
-		"goal":                goal,
-		"progress_percentage": goal.GetProgressPercentage(),
-	})
-}
\ No newline at end of file
+		"goal":                movement.Goal,
+		"progress_percentage": movement.Goal.GetProgressPercentage(),
+		"insight":             goalInsight(ctx, movement.Goal),
+		"contribution":        movement.Contribution,
+	}
+	if movement.Transaction != nil {
+		response["transaction"] = movement.Transaction
+	}
+
+	c.JSON(http.StatusOK, response)
+}
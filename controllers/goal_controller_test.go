@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMoveGoalFundsConcurrent(t *testing.T) {
+	ctx := setupTestDB(t)
+
+	userID := primitive.NewObjectID()
+	goal := models.FinancialGoal{
+		ID:            primitive.NewObjectID(),
+		UserID:        userID,
+		Nama:          "Dana Darurat",
+		TargetAmount:  models.Money(1_000_000_00),
+		CurrentAmount: models.Money(50_000_00),
+		Status:        "active",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if _, err := config.GetCollection("financial_goals").InsertOne(ctx, goal); err != nil {
+		t.Fatalf("insert goal: %v", err)
+	}
+
+	const workers = 40
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		expected  = goal.CurrentAmount
+		succeeded int
+		rejected  int
+	)
+
+	for i := 0; i < workers; i++ {
+		direction := "tarik"
+		amount := models.Money(30_000_00)
+		if i%4 == 0 {
+			direction = "setor"
+			amount = models.Money(10_000_00)
+		}
+
+		wg.Add(1)
+		go func(direction string, amount models.Money) {
+			defer wg.Done()
+
+			result, err := moveGoalFunds(ctx, goalFundsMovement{
+				UserID:    userID,
+				GoalID:    goal.ID,
+				Direction: direction,
+				Amount:    amount,
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, errInsufficientGoalFunds):
+				rejected++
+			case err != nil:
+				t.Errorf("%s %s: %v", direction, amount, err)
+			default:
+				succeeded++
+				if result.Goal.CurrentAmount < 0 {
+					t.Errorf("current_amount went negative: %s", result.Goal.CurrentAmount)
+				}
+				if direction == "setor" {
+					expected += amount
+				} else {
+					expected -= amount
+				}
+			}
+		}(direction, amount)
+	}
+	wg.Wait()
+
+	if succeeded+rejected != workers {
+		t.Fatalf("got %d successful and %d rejected moves, want %d in total", succeeded, rejected, workers)
+	}
+	if rejected == 0 {
+		t.Errorf("expected some withdrawals to be rejected for insufficient funds")
+	}
+
+	var final models.FinancialGoal
+	if err := config.GetCollection("financial_goals").FindOne(ctx, bson.M{"_id": goal.ID}).Decode(&final); err != nil {
+		t.Fatalf("find goal: %v", err)
+	}
+	if final.CurrentAmount < 0 {
+		t.Errorf("final current_amount is negative: %s", final.CurrentAmount)
+	}
+	if final.CurrentAmount != expected {
+		t.Errorf("final current_amount = %s, want %s", final.CurrentAmount, expected)
+	}
+
+	cursor, err := config.GetCollection("goal_contributions").Find(ctx, bson.M{"goal_id": goal.ID})
+	if err != nil {
+		t.Fatalf("find contributions: %v", err)
+	}
+	var contributions []models.GoalContribution
+	if err := cursor.All(ctx, &contributions); err != nil {
+		t.Fatalf("decode contributions: %v", err)
+	}
+	if len(contributions) != succeeded {
+		t.Errorf("got %d goal_contributions, want %d", len(contributions), succeeded)
+	}
+
+	ledger := goal.CurrentAmount
+	for _, contribution := range contributions {
+		if contribution.Direction == "setor" {
+			ledger += contribution.Amount
+		} else {
+			ledger -= contribution.Amount
+		}
+	}
+	if ledger != final.CurrentAmount {
+		t.Errorf("contribution ledger totals %s, goal balance is %s", ledger, final.CurrentAmount)
+	}
+}
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// refreshGoalStatuses memberi status "active" pada goal lama yang belum
+// memiliki status dan membuka kunci goal yang locked_until-nya sudah lewat
+func refreshGoalStatuses(ctx context.Context, userID primitive.ObjectID) error {
+	collection := config.GetCollection("financial_goals")
+
+	_, err := collection.UpdateMany(ctx,
+		withAccess(bson.M{"status": bson.M{"$in": bson.A{nil, ""}}}, userID, "viewer"),
+		bson.M{"$set": bson.M{"status": "active"}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateMany(ctx,
+		withAccess(bson.M{"status": "locked", "locked_until": bson.M{"$lte": time.Now()}}, userID, "viewer"),
+		bson.A{bson.M{"$set": bson.M{
+			"status": bson.M{"$cond": bson.A{
+				bson.M{"$ifNull": bson.A{"$completed_at", false}}, "completed", "active",
+			}},
+			"updated_at": time.Now(),
+		}}},
+	)
+	return err
+}
+
+// syncGoalCompletion menandai goal aktif yang sudah mencapai target sebagai
+// completed, atau mengembalikannya ke active jika target dinaikkan melebihi
+// saldo. completed_at hanya diisi sekali saat target pertama kali tercapai.
+func syncGoalCompletion(ctx context.Context, goalID primitive.ObjectID) (*models.FinancialGoal, error) {
+	reached := bson.M{"$gte": bson.A{"$current_amount", "$target_amount"}}
+
+	var goal models.FinancialGoal
+	err := config.GetCollection("financial_goals").FindOneAndUpdate(ctx,
+		bson.M{"_id": goalID},
+		bson.A{bson.M{"$set": bson.M{
+			"completed_at": bson.M{"$cond": bson.A{
+				reached, bson.M{"$ifNull": bson.A{"$completed_at", time.Now()}}, "$$REMOVE",
+			}},
+			"status": bson.M{"$switch": bson.M{
+				"branches": bson.A{
+					bson.M{"case": bson.M{"$and": bson.A{reached, bson.M{"$eq": bson.A{"$status", "active"}}}}, "then": "completed"},
+					bson.M{"case": bson.M{"$and": bson.A{bson.M{"$not": bson.A{reached}}, bson.M{"$eq": bson.A{"$status", "completed"}}}}, "then": "active"},
+				},
+				"default": "$status",
+			}},
+		}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&goal)
+	if err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+// LockGoal - Mengunci dana goal sampai tanggal tertentu
+func LockGoal(c *gin.Context) {
+	var input models.LockGoalInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lockedUntil, err := time.Parse("2006-01-02", input.LockedUntil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format locked_until tidak valid. Gunakan format YYYY-MM-DD"})
+		return
+	}
+	if !lockedUntil.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "locked_until harus di masa depan"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	goal, ok := findUserGoal(ctx, c, "owner")
+	if !ok {
+		return
+	}
+
+	if goal.Status == "archived" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Goal yang diarsipkan tidak dapat dikunci"})
+		return
+	}
+	// Kunci hanya bisa diperpanjang, tidak dapat dipersingkat
+	if goal.IsLocked(time.Now()) && lockedUntil.Before(*goal.LockedUntil) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":        "Goal sudah terkunci sampai tanggal yang lebih lama",
+			"locked_until": goal.LockedUntil,
+		})
+		return
+	}
+
+	var updated models.FinancialGoal
+	err = config.GetCollection("financial_goals").FindOneAndUpdate(ctx,
+		bson.M{"_id": goal.ID, "user_id": goal.UserID},
+		bson.M{"$set": bson.M{
+			"status":       "locked",
+			"locked_until": lockedUntil,
+			"updated_at":   time.Now(),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock goal"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Goal berhasil dikunci",
+		"goal":    updated,
+	})
+}
+
+// ArchiveGoal - Menyembunyikan goal dari daftar goal
+func ArchiveGoal(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	goal, ok := findUserGoal(ctx, c, "owner")
+	if !ok {
+		return
+	}
+
+	if goal.IsLocked(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":        "Goal yang terkunci tidak dapat diarsipkan",
+			"locked_until": goal.LockedUntil,
+		})
+		return
+	}
+
+	var updated models.FinancialGoal
+	err := config.GetCollection("financial_goals").FindOneAndUpdate(ctx,
+		bson.M{"_id": goal.ID, "user_id": goal.UserID},
+		bson.M{
+			"$set":   bson.M{"status": "archived", "updated_at": time.Now()},
+			"$unset": bson.M{"locked_until": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to archive goal"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Goal berhasil diarsipkan",
+		"goal":    updated,
+	})
+}
+
+// RestoreGoal - Mengembalikan goal yang diarsipkan
+func RestoreGoal(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	goal, ok := findUserGoal(ctx, c, "owner")
+	if !ok {
+		return
+	}
+
+	if goal.Status != "archived" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Goal tidak sedang diarsipkan"})
+		return
+	}
+
+	status := "active"
+	if goal.CompletedAt != nil {
+		status = "completed"
+	}
+
+	var updated models.FinancialGoal
+	err := config.GetCollection("financial_goals").FindOneAndUpdate(ctx,
+		bson.M{"_id": goal.ID, "user_id": goal.UserID, "status": "archived"},
+		bson.M{"$set": bson.M{"status": status, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore goal"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Goal berhasil dikembalikan",
+		"goal":    updated,
+	})
+}
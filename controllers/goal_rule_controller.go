@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// applyGoalRules menjalankan aturan tabungan otomatis milik user untuk
+// transaksi yang baru dibuat. Aturan yang gagal (misalnya goal sudah
+// diarsipkan) dilewati tanpa menggagalkan transaksinya. Saldo goal dalam
+// mata uang dasar, sehingga transaksi mata uang asing tidak memicu aturan.
+func applyGoalRules(ctx context.Context, transaction models.Transaction) []models.GoalContribution {
+	contributions := []models.GoalContribution{}
+	if transaction.Tipe != "pemasukan" && transaction.Tipe != "pengeluaran" {
+		return contributions
+	}
+	if transaction.Currency != "" {
+		base, err := getBaseCurrency(ctx, transaction.UserID)
+		if err != nil || transaction.Currency != base {
+			return contributions
+		}
+	}
+
+	cursor, err := config.GetCollection("goal_rules").Find(ctx, bson.M{
+		"user_id":   transaction.UserID,
+		"is_active": true,
+	}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return contributions
+	}
+
+	var rules []models.GoalRule
+	if err := cursor.All(ctx, &rules); err != nil {
+		return contributions
+	}
+
+	for _, rule := range rules {
+		amount := rule.AmountFor(&transaction)
+		if amount <= 0 {
+			continue
+		}
+
+		catatan := fmt.Sprintf("Otomatis: %g%% dari %s", rule.Persentase, transaction.Kategori)
+		if rule.Tipe == "pembulatan" {
+			catatan = fmt.Sprintf("Otomatis: pembulatan %s ke kelipatan %s", transaction.Kategori, rule.Kelipatan)
+		}
+
+		movement, err := moveGoalFunds(ctx, goalFundsMovement{
+			UserID:            transaction.UserID,
+			GoalID:            rule.GoalID,
+			Direction:         "setor",
+			Amount:            amount,
+			RecordTransaction: rule.RecordTransaction,
+			AccountID:         transaction.AccountID,
+			Catatan:           catatan,
+			RuleID:            &rule.ID,
+			SourceID:          &transaction.ID,
+		})
+		if err != nil {
+			continue
+		}
+		contributions = append(contributions, *movement.Contribution)
+	}
+
+	return contributions
+}
+
+// findUserGoalRule mengambil aturan dari parameter :ruleId milik goal tersebut
+func findUserGoalRule(ctx context.Context, c *gin.Context, goal *models.FinancialGoal) (*models.GoalRule, bool) {
+	ruleID, err := primitive.ObjectIDFromHex(c.Param("ruleId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return nil, false
+	}
+
+	var rule models.GoalRule
+	err = config.GetCollection("goal_rules").FindOne(ctx, bson.M{"_id": ruleID, "goal_id": goal.ID}).Decode(&rule)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Aturan tidak ditemukan"})
+		return nil, false
+	}
+	return &rule, true
+}
+
+// validateGoalRuleCategory memastikan kategori aturan ada untuk tipe transaksinya
+func validateGoalRuleCategory(ctx context.Context, c *gin.Context, rule *models.GoalRule) bool {
+	if rule.Kategori == "" {
+		return true
+	}
+
+	valid, allowed, err := validateUserCategory(ctx, rule.UserID, rule.TransactionTipe(), rule.Kategori)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate category"})
+		return false
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":              "Kategori tidak valid",
+			"allowed_categories": allowed,
+		})
+		return false
+	}
+	return true
+}
+
+// CreateGoalRule - Menambahkan aturan tabungan otomatis pada goal
+func CreateGoalRule(c *gin.Context) {
+	var input models.CreateGoalRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Tipe == "persentase" && input.Persentase == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "persentase wajib diisi untuk aturan persentase"})
+		return
+	}
+	if input.Tipe == "pembulatan" && input.Kelipatan == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kelipatan wajib diisi untuk aturan pembulatan"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	goal, ok := findUserGoal(ctx, c, "editor")
+	if !ok {
+		return
+	}
+
+	if goal.Status == "archived" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Goal sudah diarsipkan, kembalikan goal terlebih dahulu"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	// Aturan dijalankan untuk transaksi milik pembuat aturan
+	rule := models.GoalRule{
+		ID:                primitive.NewObjectID(),
+		UserID:            userObjectID,
+		GoalID:            goal.ID,
+		Tipe:              input.Tipe,
+		Kategori:          input.Kategori,
+		RecordTransaction: input.RecordTransaction,
+		IsActive:          true,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+	}
+	if rule.Tipe == "persentase" {
+		rule.Persentase = input.Persentase
+	} else {
+		rule.Kelipatan = input.Kelipatan
+	}
+
+	if !validateGoalRuleCategory(ctx, c, &rule) {
+		return
+	}
+
+	_, err := config.GetCollection("goal_rules").InsertOne(ctx, rule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create goal rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Aturan berhasil dibuat",
+		"rule":    rule,
+	})
+}
+
+// GetGoalRules - Daftar aturan tabungan otomatis pada goal
+func GetGoalRules(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	goal, ok := findUserGoal(ctx, c, "viewer")
+	if !ok {
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := config.GetCollection("goal_rules").Find(ctx, bson.M{"goal_id": goal.ID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch goal rules"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	rules := []models.GoalRule{}
+	if err := cursor.All(ctx, &rules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode goal rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules": rules,
+		"count": len(rules),
+	})
+}
+
+// UpdateGoalRule - Mengubah atau mengaktifkan/menonaktifkan aturan
+func UpdateGoalRule(c *gin.Context) {
+	var input models.UpdateGoalRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	goal, ok := findUserGoal(ctx, c, "editor")
+	if !ok {
+		return
+	}
+	rule, ok := findUserGoalRule(ctx, c, goal)
+	if !ok {
+		return
+	}
+
+	update := bson.M{"updated_at": time.Now()}
+	if input.Persentase > 0 {
+		if rule.Tipe != "persentase" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "persentase hanya berlaku untuk aturan persentase"})
+			return
+		}
+		update["persentase"] = input.Persentase
+	}
+	if input.Kelipatan > 0 {
+		if rule.Tipe != "pembulatan" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "kelipatan hanya berlaku untuk aturan pembulatan"})
+			return
+		}
+		update["kelipatan"] = input.Kelipatan
+	}
+	if input.Kategori != nil {
+		rule.Kategori = *input.Kategori
+		if !validateGoalRuleCategory(ctx, c, rule) {
+			return
+		}
+		update["kategori"] = *input.Kategori
+	}
+	if input.RecordTransaction != nil {
+		update["record_transaction"] = *input.RecordTransaction
+	}
+	if input.IsActive != nil {
+		update["is_active"] = *input.IsActive
+	}
+
+	var updated models.GoalRule
+	err := config.GetCollection("goal_rules").FindOneAndUpdate(ctx,
+		bson.M{"_id": rule.ID},
+		bson.M{"$set": update},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update goal rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Aturan berhasil diperbarui",
+		"rule":    updated,
+	})
+}
+
+// DeleteGoalRule - Menghapus aturan tabungan otomatis
+func DeleteGoalRule(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	goal, ok := findUserGoal(ctx, c, "editor")
+	if !ok {
+		return
+	}
+	rule, ok := findUserGoalRule(ctx, c, goal)
+	if !ok {
+		return
+	}
+
+	_, err := config.GetCollection("goal_rules").DeleteOne(ctx, bson.M{"_id": rule.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete goal rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Aturan berhasil dihapus"})
+}
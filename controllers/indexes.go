@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// EnsureIndexes membuat index yang dibutuhkan untuk menjaga konsistensi data.
+// Dipanggil setiap kali koneksi database dibuat, baik di server biasa maupun
+// serverless, karena beberapa handler bergantung pada unique index.
+func EnsureIndexes(ctx context.Context) error {
+	indexes := []struct {
+		name   string
+		ensure func(context.Context) error
+	}{
+		{"recurring transaction", EnsureRecurringIndexes},
+		{"bill payment", EnsureBillIndexes},
+		{"bill calendar token", EnsureBillCalendarIndexes},
+		{"exchange rate", EnsureExchangeRateIndexes},
+	}
+
+	var errs []error
+	for _, index := range indexes {
+		if err := index.ensure(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s index: %w", index.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
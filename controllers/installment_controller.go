@@ -0,0 +1,461 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InstallmentWithProgress adalah cicilan beserta sisa tenor dan sisa tagihannya
+type InstallmentWithProgress struct {
+	models.Installment
+	Progress models.InstallmentProgress `json:"progress"`
+}
+
+func withInstallmentProgress(in models.Installment) InstallmentWithProgress {
+	return InstallmentWithProgress{Installment: in, Progress: in.Progress()}
+}
+
+// applyInstallmentPayment menandai angsuran berikutnya lunas jika transaksi
+// terhubung langsung ke cicilan (installment_id) atau cocok dengan angsuran
+// berikutnya (akun, kategori dan nominal sama). Mengembalikan nil jika tidak ada
+// angsuran yang dibayar.
+func applyInstallmentPayment(ctx context.Context, transaction *models.Transaction) *InstallmentWithProgress {
+	if transaction.Tipe != "pengeluaran" {
+		return nil
+	}
+
+	collection := config.GetCollection("installments")
+	var installment *models.Installment
+
+	if transaction.InstallmentID != nil {
+		var linked models.Installment
+		err := collection.FindOne(ctx, bson.M{
+			"_id":     *transaction.InstallmentID,
+			"user_id": transaction.UserID,
+			"status":  "aktif",
+		}).Decode(&linked)
+		if err != nil {
+			return nil
+		}
+		installment = &linked
+	} else {
+		if transaction.AccountID == nil || transaction.RecurringID != nil {
+			return nil
+		}
+		opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+		cursor, err := collection.Find(ctx, bson.M{
+			"user_id":    transaction.UserID,
+			"status":     "aktif",
+			"kategori":   transaction.Kategori,
+			"account_id": *transaction.AccountID,
+		}, opts)
+		if err != nil {
+			return nil
+		}
+		var candidates []models.Installment
+		if err := cursor.All(ctx, &candidates); err != nil {
+			return nil
+		}
+		for i := range candidates {
+			if candidates[i].Matches(transaction) {
+				installment = &candidates[i]
+				break
+			}
+		}
+	}
+
+	if installment == nil {
+		return nil
+	}
+	due := installment.NextUnpaid()
+	if due == nil {
+		return nil
+	}
+
+	// Hanya berhasil jika angsuran tersebut belum dibayar oleh transaksi lain
+	result, err := collection.UpdateOne(ctx,
+		bson.M{
+			"_id":      installment.ID,
+			"schedule": bson.M{"$elemMatch": bson.M{"ke": due.Ke, "status": "belum"}},
+		},
+		bson.M{"$set": bson.M{
+			"schedule.$.status":         "lunas",
+			"schedule.$.paid_at":        transaction.Tanggal,
+			"schedule.$.transaction_id": transaction.ID,
+			"updated_at":                time.Now(),
+		}},
+	)
+	if err != nil || result.ModifiedCount == 0 {
+		return nil
+	}
+
+	collection.UpdateOne(ctx,
+		bson.M{"_id": installment.ID, "schedule.status": bson.M{"$ne": "belum"}},
+		bson.M{"$set": bson.M{"status": "lunas"}},
+	)
+
+	if transaction.InstallmentID == nil {
+		transaction.InstallmentID = &installment.ID
+		config.GetCollection("transactions").UpdateOne(ctx,
+			bson.M{"_id": transaction.ID},
+			bson.M{"$set": bson.M{"installment_id": installment.ID}},
+		)
+	}
+
+	var updated models.Installment
+	if err := collection.FindOne(ctx, bson.M{"_id": installment.ID}).Decode(&updated); err != nil {
+		return nil
+	}
+	paid := withInstallmentProgress(updated)
+	return &paid
+}
+
+// releaseInstallmentPayment mengembalikan angsuran yang dibayar oleh
+// transaksi yang dihapus menjadi belum dibayar
+func releaseInstallmentPayment(ctx context.Context, transactionID primitive.ObjectID) {
+	config.GetCollection("installments").UpdateOne(ctx,
+		bson.M{"schedule.transaction_id": transactionID},
+		bson.M{
+			"$set": bson.M{
+				"schedule.$.status": "belum",
+				"status":            "aktif",
+				"updated_at":        time.Now(),
+			},
+			"$unset": bson.M{
+				"schedule.$.paid_at":        "",
+				"schedule.$.transaction_id": "",
+			},
+		},
+	)
+}
+
+func CreateInstallment(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.CreateInstallmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	firstDue, err := time.Parse("2006-01-02", input.FirstDueDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format first_due_date tidak valid. Gunakan format YYYY-MM-DD"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	valid, allowed, err := validateUserCategory(ctx, objectID, "pengeluaran", input.Kategori)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate category"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":              "Kategori tidak valid",
+			"allowed_categories": allowed,
+		})
+		return
+	}
+
+	var accountID *primitive.ObjectID
+	if input.AccountID != "" {
+		account, err := findUserAccount(ctx, objectID, input.AccountID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak ditemukan"})
+			return
+		}
+		accountID = &account.ID
+	}
+
+	installment := models.Installment{
+		ID:            primitive.NewObjectID(),
+		UserID:        objectID,
+		Nama:          input.Nama,
+		Tipe:          input.Tipe,
+		Provider:      input.Provider,
+		Kategori:      input.Kategori,
+		AccountID:     accountID,
+		MonthlyAmount: input.MonthlyAmount,
+		Tenor:         input.Tenor,
+		Schedule:      models.BuildInstallmentSchedule(firstDue, input.Tenor, input.MonthlyAmount),
+		Status:        "aktif",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	_, err = config.GetCollection("installments").InsertOne(ctx, installment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create installment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Cicilan berhasil dibuat",
+		"installment": withInstallmentProgress(installment),
+	})
+}
+
+func GetInstallments(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": objectID}
+	if status := c.Query("status"); status == "aktif" || status == "lunas" {
+		filter["status"] = status
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := config.GetCollection("installments").Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch installments"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var installments []models.Installment
+	if err := cursor.All(ctx, &installments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode installments"})
+		return
+	}
+
+	result := []InstallmentWithProgress{}
+	var totalOutstanding models.Money
+	for _, in := range installments {
+		item := withInstallmentProgress(in)
+		totalOutstanding += item.Progress.Outstanding
+		result = append(result, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"installments":      result,
+		"count":             len(result),
+		"total_outstanding": totalOutstanding,
+	})
+}
+
+func GetInstallmentByID(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid installment ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var installment models.Installment
+	err = config.GetCollection("installments").FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&installment)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cicilan tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"installment": withInstallmentProgress(installment)})
+}
+
+// GetUpcomingInstallments - Angsuran yang belum dibayar dan jatuh tempo dalam N hari ke depan
+func GetUpcomingInstallments(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	days := 30
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days harus berupa angka 1-365"})
+			return
+		}
+		days = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := config.GetCollection("installments").Find(ctx, bson.M{"user_id": objectID, "status": "aktif"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch installments"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var installments []models.Installment
+	if err := cursor.All(ctx, &installments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode installments"})
+		return
+	}
+
+	// Angsuran yang sudah lewat jatuh tempo tetap ditampilkan sampai dibayar
+	now := today(time.Now())
+	until := now.AddDate(0, 0, days)
+	upcoming := []models.UpcomingInstallmentDue{}
+	var total models.Money
+	for _, in := range installments {
+		for _, due := range in.Schedule {
+			if due.Status == "lunas" || due.DueDate.After(until) {
+				continue
+			}
+			upcoming = append(upcoming, models.UpcomingInstallmentDue{
+				InstallmentID:  in.ID,
+				Nama:           in.Nama,
+				Provider:       in.Provider,
+				InstallmentDue: due,
+				IsOverdue:      due.DueDate.Before(now),
+			})
+			total += due.Amount
+		}
+	}
+
+	sort.Slice(upcoming, func(i, j int) bool {
+		return upcoming[i].DueDate.Before(upcoming[j].DueDate)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":     days,
+		"upcoming": upcoming,
+		"count":    len(upcoming),
+		"total":    total,
+	})
+}
+
+func UpdateInstallment(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid installment ID"})
+		return
+	}
+
+	var input models.UpdateInstallmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+	if input.Nama != "" {
+		set["nama"] = input.Nama
+	}
+	if input.Provider != nil {
+		set["provider"] = *input.Provider
+	}
+	if input.Kategori != "" {
+		valid, allowed, err := validateUserCategory(ctx, userObjectID, "pengeluaran", input.Kategori)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate category"})
+			return
+		}
+		if !valid {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":              "Kategori tidak valid",
+				"allowed_categories": allowed,
+			})
+			return
+		}
+		set["kategori"] = input.Kategori
+	}
+	if input.AccountID != nil {
+		if *input.AccountID == "" {
+			unset["account_id"] = ""
+		} else {
+			account, err := findUserAccount(ctx, userObjectID, *input.AccountID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak ditemukan"})
+				return
+			}
+			set["account_id"] = account.ID
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var installment models.Installment
+	err = config.GetCollection("installments").FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "user_id": userObjectID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&installment)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cicilan tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Cicilan berhasil diperbarui",
+		"installment": withInstallmentProgress(installment),
+	})
+}
+
+func DeleteInstallment(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid installment ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := config.GetCollection("installments").DeleteOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete installment"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cicilan tidak ditemukan"})
+		return
+	}
+
+	// Transaksi pembayaran tetap disimpan, namun tidak lagi terhubung dengan cicilan
+	config.GetCollection("transactions").UpdateMany(ctx,
+		bson.M{"installment_id": objectID},
+		bson.M{"$unset": bson.M{"installment_id": ""}},
+	)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cicilan berhasil dihapus"})
+}
@@ -0,0 +1,403 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sharedCollections memetakan resource_type undangan ke koleksinya
+var sharedCollections = map[string]string{
+	"goal":    "financial_goals",
+	"account": "accounts",
+}
+
+// accessFilter mengembalikan filter goal/akun yang dapat diakses user
+// dengan peran minimal minRole ("viewer", "editor" atau "owner")
+func accessFilter(userID primitive.ObjectID, minRole string) bson.M {
+	var roles []string
+	switch minRole {
+	case "owner":
+		return bson.M{"user_id": userID}
+	case "editor":
+		roles = []string{"editor"}
+	default:
+		roles = []string{"editor", "viewer"}
+	}
+
+	return bson.M{"$or": []bson.M{
+		{"user_id": userID},
+		{"members": bson.M{"$elemMatch": bson.M{"user_id": userID, "role": bson.M{"$in": roles}}}},
+	}}
+}
+
+// withAccess menambahkan accessFilter ke filter tanpa menimpa $or lain
+func withAccess(filter bson.M, userID primitive.ObjectID, minRole string) bson.M {
+	filter["$and"] = []bson.M{accessFilter(userID, minRole)}
+	return filter
+}
+
+// roleLevels mengurutkan peran dari akses terendah
+var roleLevels = map[string]int{"viewer": 1, "editor": 2, "owner": 3}
+
+// hasRole reports whether role grants at least minRole
+func hasRole(role, minRole string) bool {
+	return role != "" && roleLevels[role] >= roleLevels[minRole]
+}
+
+// accessibleAccountIDs mengembalikan ID akun yang dapat diakses user
+func accessibleAccountIDs(ctx context.Context, userID primitive.ObjectID, minRole string) ([]primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := config.GetCollection("accounts").Find(ctx, accessFilter(userID, minRole), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(accounts))
+	for _, a := range accounts {
+		ids = append(ids, a.ID)
+	}
+	return ids, nil
+}
+
+// transactionAccessFilter mengembalikan filter transaksi milik user sendiri
+// ditambah transaksi anggota lain pada akun bersama yang dapat diakses user
+func transactionAccessFilter(ctx context.Context, userID primitive.ObjectID, minRole string) (bson.M, error) {
+	accountIDs, err := accessibleAccountIDs(ctx, userID, minRole)
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.M{"$or": []bson.M{
+		{"user_id": userID},
+		{"account_id": bson.M{"$in": accountIDs}},
+		{"to_account_id": bson.M{"$in": accountIDs}},
+	}}, nil
+}
+
+// sharedResource adalah field yang sama pada goal dan akun bersama
+type sharedResource struct {
+	ID      primitive.ObjectID `bson:"_id"`
+	UserID  primitive.ObjectID `bson:"user_id"`
+	Nama    string             `bson:"nama"`
+	Members []models.Member    `bson:"members"`
+}
+
+func inviteMember(c *gin.Context, resourceType string) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	resourceID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + resourceType + " ID"})
+		return
+	}
+
+	var input models.InviteMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Hanya pemilik yang dapat mengundang anggota
+	var resource sharedResource
+	err = config.GetCollection(sharedCollections[resourceType]).FindOne(ctx, bson.M{"_id": resourceID, "user_id": userObjectID}).Decode(&resource)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Data tidak ditemukan atau Anda bukan pemiliknya"})
+		return
+	}
+
+	usersCollection := config.GetCollection("users")
+	var inviter, invitee models.User
+	if err := usersCollection.FindOne(ctx, bson.M{"_id": userObjectID}).Decode(&inviter); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := usersCollection.FindOne(ctx, bson.M{"username": input.Username}).Decode(&invitee); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Username tidak ditemukan"})
+		return
+	}
+
+	if invitee.ID == userObjectID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tidak dapat mengundang diri sendiri"})
+		return
+	}
+	for _, m := range resource.Members {
+		if m.UserID == invitee.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "User sudah menjadi anggota"})
+			return
+		}
+	}
+
+	collection := config.GetCollection("invitations")
+	pending, err := collection.CountDocuments(ctx, bson.M{
+		"resource_type": resourceType,
+		"resource_id":   resourceID,
+		"invitee_id":    invitee.ID,
+		"status":        "pending",
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+	if pending > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User sudah memiliki undangan yang belum dijawab"})
+		return
+	}
+
+	invitation := models.Invitation{
+		ID:              primitive.NewObjectID(),
+		ResourceType:    resourceType,
+		ResourceID:      resourceID,
+		ResourceNama:    resource.Nama,
+		InviterID:       userObjectID,
+		InviterUsername: inviter.Username,
+		InviteeID:       invitee.ID,
+		InviteeUsername: invitee.Username,
+		Role:            input.Role,
+		Status:          "pending",
+		CreatedAt:       time.Now(),
+	}
+
+	if _, err := collection.InsertOne(ctx, invitation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Undangan berhasil dikirim",
+		"invitation": invitation,
+	})
+}
+
+func updateMemberRole(c *gin.Context, resourceType string) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	resourceID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + resourceType + " ID"})
+		return
+	}
+	memberID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.UpdateMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Hanya pemilik yang dapat mengubah peran anggota
+	result, err := config.GetCollection(sharedCollections[resourceType]).UpdateOne(ctx,
+		bson.M{"_id": resourceID, "user_id": userObjectID, "members.user_id": memberID},
+		bson.M{"$set": bson.M{"members.$.role": input.Role, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Anggota tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Peran anggota berhasil diperbarui"})
+}
+
+func removeMember(c *gin.Context, resourceType string) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	resourceID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + resourceType + " ID"})
+		return
+	}
+	memberID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Pemilik dapat mengeluarkan anggota, anggota dapat keluar sendiri
+	filter := bson.M{"_id": resourceID, "members.user_id": memberID}
+	if memberID != userObjectID {
+		filter["user_id"] = userObjectID
+	}
+
+	result, err := config.GetCollection(sharedCollections[resourceType]).UpdateOne(ctx, filter, bson.M{
+		"$pull": bson.M{"members": bson.M{"user_id": memberID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Anggota tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Anggota berhasil dihapus"})
+}
+
+// InviteGoalMember - Mengundang user lain ke goal bersama
+func InviteGoalMember(c *gin.Context) { inviteMember(c, "goal") }
+
+// UpdateGoalMember - Mengubah peran anggota goal
+func UpdateGoalMember(c *gin.Context) { updateMemberRole(c, "goal") }
+
+// RemoveGoalMember - Mengeluarkan anggota goal atau keluar dari goal
+func RemoveGoalMember(c *gin.Context) { removeMember(c, "goal") }
+
+// InviteAccountMember - Mengundang user lain ke akun bersama
+func InviteAccountMember(c *gin.Context) { inviteMember(c, "account") }
+
+// UpdateAccountMember - Mengubah peran anggota akun
+func UpdateAccountMember(c *gin.Context) { updateMemberRole(c, "account") }
+
+// RemoveAccountMember - Mengeluarkan anggota akun atau keluar dari akun
+func RemoveAccountMember(c *gin.Context) { removeMember(c, "account") }
+
+// GetInvitations - Undangan yang belum dijawab untuk user yang sedang login
+func GetInvitations(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := config.GetCollection("invitations").Find(ctx, bson.M{"invitee_id": objectID, "status": "pending"}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []models.Invitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitations": invitations,
+		"count":       len(invitations),
+	})
+}
+
+// respondInvitation menandai undangan pending sebagai accepted/declined
+func respondInvitation(ctx context.Context, c *gin.Context, status string) (*models.Invitation, bool) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	invitationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return nil, false
+	}
+
+	var invitation models.Invitation
+	err = config.GetCollection("invitations").FindOneAndUpdate(ctx,
+		bson.M{"_id": invitationID, "invitee_id": userObjectID, "status": "pending"},
+		bson.M{"$set": bson.M{"status": status, "responded_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&invitation)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Undangan tidak ditemukan"})
+		return nil, false
+	}
+	return &invitation, true
+}
+
+// AcceptInvitation - Menerima undangan dan bergabung sebagai anggota
+func AcceptInvitation(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invitation, ok := respondInvitation(ctx, c, "accepted")
+	if !ok {
+		return
+	}
+
+	member := models.Member{
+		UserID:   invitation.InviteeID,
+		Username: invitation.InviteeUsername,
+		Role:     invitation.Role,
+		JoinedAt: time.Now(),
+	}
+
+	// Tidak menambahkan anggota dua kali jika undangan diterima bersamaan
+	result, err := config.GetCollection(sharedCollections[invitation.ResourceType]).UpdateOne(ctx,
+		bson.M{"_id": invitation.ResourceID, "members.user_id": bson.M{"$ne": member.UserID}},
+		bson.M{
+			"$push": bson.M{"members": member},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		count, _ := config.GetCollection(sharedCollections[invitation.ResourceType]).CountDocuments(ctx, bson.M{"_id": invitation.ResourceID})
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Data yang dibagikan sudah dihapus"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Undangan diterima",
+		"invitation": invitation,
+	})
+}
+
+// DeclineInvitation - Menolak undangan
+func DeclineInvitation(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invitation, ok := respondInvitation(ctx, c, "declined")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Undangan ditolak",
+		"invitation": invitation,
+	})
+}
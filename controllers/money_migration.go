@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"DompetKu/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// moneyFields adalah field nominal per koleksi yang disimpan sebagai
+// models.Money. Field di dalam array ditulis sebagai "array.field".
+var moneyFields = map[string][]string{
+	"transactions":           {"nominal", "splits.nominal"},
+	"recurring_transactions": {"nominal"},
+	"financial_goals":        {"target_amount", "current_amount"},
+	"goal_contributions":     {"amount"},
+	"goal_rules":             {"kelipatan"},
+	"accounts":               {"saldo_awal"},
+	"budgets":                {"limit"},
+	"debts":                  {"principal", "paid", "remaining"},
+	"debt_payments":          {"amount"},
+	"installments":           {"monthly_amount", "schedule.amount"},
+	"bills":                  {"amount"},
+}
+
+// legacyMoneyTypes adalah tipe BSON nominal sebelum disimpan sebagai int64
+var legacyMoneyTypes = bson.A{"double", "decimal"}
+
+// toMinorUnits mengubah nominal rupiah utuh (double/decimal) menjadi int64
+// satuan terkecil, nilai yang sudah int dibiarkan
+func toMinorUnits(expr string) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$in": bson.A{bson.M{"$type": expr}, legacyMoneyTypes}},
+		bson.M{"$toLong": bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{expr, 100}}, 0}}},
+		expr,
+	}}
+}
+
+// sumMoney menjumlahkan field Money pada $group sebagai int64 satuan terkecil.
+// Tanpa konversi, $sum yang bercampur nilai double menghasilkan double yang
+// oleh models.Money dianggap rupiah utuh sehingga dikali 100 lagi.
+func sumMoney(expr string) bson.M {
+	return bson.M{"$sum": toMinorUnits(expr)}
+}
+
+// MigrateMoneyFields mengubah nominal lama yang tersimpan sebagai float
+// menjadi int64 satuan terkecil (lihat models.Money). Hanya dokumen yang
+// masih memiliki nilai double/decimal yang diubah sehingga aman dijalankan
+// setiap kali server start.
+func MigrateMoneyFields(ctx context.Context) error {
+	for name, fields := range moneyFields {
+		collection := config.GetCollection(name)
+		for _, field := range fields {
+			filter := bson.M{field: bson.M{"$type": legacyMoneyTypes}}
+
+			var set bson.M
+			if array, sub, ok := strings.Cut(field, "."); ok {
+				set = bson.M{array: bson.M{"$map": bson.M{
+					"input": "$" + array,
+					"as":    "item",
+					"in": bson.M{"$mergeObjects": bson.A{
+						"$$item",
+						bson.M{sub: toMinorUnits("$$item." + sub)},
+					}},
+				}}}
+			} else {
+				set = bson.M{field: toMinorUnits("$" + field)}
+			}
+
+			if _, err := collection.UpdateMany(ctx, filter, bson.A{bson.M{"$set": set}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
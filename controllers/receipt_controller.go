@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+	"DompetKu/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxReceiptsPerTransaction = 5
+	maxReceiptSize            = 5 << 20 // 5 MB per file
+)
+
+// allowedReceiptTypes adalah tipe file struk yang diterima, dideteksi dari isi file
+var allowedReceiptTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// readReceipt membaca file struk dan memvalidasi ukuran serta tipe filenya.
+// Tipe ditentukan dari isi file, bukan dari Content-Type yang dikirim client.
+func readReceipt(fileHeader *multipart.FileHeader) ([]byte, string, string) {
+	if fileHeader.Size > maxReceiptSize {
+		return nil, "", "Ukuran file " + fileHeader.Filename + " melebihi 5 MB"
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, "", "File " + fileHeader.Filename + " tidak dapat dibaca"
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxReceiptSize+1))
+	if err != nil {
+		return nil, "", "File " + fileHeader.Filename + " tidak dapat dibaca"
+	}
+	if len(data) > maxReceiptSize {
+		return nil, "", "Ukuran file " + fileHeader.Filename + " melebihi 5 MB"
+	}
+
+	mimeType := http.DetectContentType(data)
+	if !allowedReceiptTypes[mimeType] {
+		return nil, "", "File " + fileHeader.Filename + " bukan gambar JPEG, PNG atau WebP"
+	}
+	return data, mimeType, ""
+}
+
+// deleteReceiptFiles menghapus file struk dari storage. Kegagalan diabaikan
+// karena struk sudah tidak dirujuk transaksi mana pun.
+func deleteReceiptFiles(ctx context.Context, receipts []models.Receipt) {
+	for _, receipt := range receipts {
+		utils.GetStorage().Delete(ctx, receipt.FileID)
+	}
+}
+
+// uploadReceipts mengupload file struk yang sudah divalidasi ke storage. Jika
+// salah satu upload gagal, file yang sudah terupload dihapus kembali.
+func uploadReceipts(ctx context.Context, files []*multipart.FileHeader, contents [][]byte, mimeTypes []string) ([]models.Receipt, error) {
+	receipts := make([]models.Receipt, 0, len(files))
+	for i, fileHeader := range files {
+		filename := utils.UniqueFileName("receipt", fileHeader.Filename)
+		uploaded, err := utils.GetStorage().Upload(ctx, bytes.NewReader(contents[i]), filename, "Dompetku/receipts")
+		if err != nil {
+			deleteReceiptFiles(ctx, receipts)
+			return nil, err
+		}
+		receipts = append(receipts, models.Receipt{
+			FileID:     uploaded.FileID,
+			URL:        uploaded.URL,
+			Nama:       fileHeader.Filename,
+			MimeType:   mimeTypes[i],
+			Size:       int64(len(contents[i])),
+			UploadedAt: time.Now(),
+		})
+	}
+	return receipts, nil
+}
+
+// UploadTransactionReceipts melampirkan satu atau beberapa foto struk
+// (multipart, field "receipts") pada transaksi pemasukan atau pengeluaran
+func UploadTransactionReceipts(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["receipts"]) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pilih minimal satu file struk pada field receipts"})
+		return
+	}
+	files := form.File["receipts"]
+	if len(files) > maxReceiptsPerTransaction {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Maksimal 5 struk per transaksi"})
+		return
+	}
+
+	collection := config.GetCollection("transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	access, err := transactionAccessFilter(ctx, userObjectID, "editor")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transaction"})
+		return
+	}
+
+	var transaction models.Transaction
+	err = collection.FindOne(ctx, bson.M{"_id": objectID, "$and": []bson.M{access}}).Decode(&transaction)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaksi tidak ditemukan"})
+		return
+	}
+	if transaction.Tipe == "transfer" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Struk hanya dapat dilampirkan pada pemasukan atau pengeluaran"})
+		return
+	}
+	if len(transaction.Receipts)+len(files) > maxReceiptsPerTransaction {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Maksimal 5 struk per transaksi"})
+		return
+	}
+
+	// Semua file divalidasi sebelum ada yang diupload
+	contents := make([][]byte, len(files))
+	mimeTypes := make([]string, len(files))
+	for i, fileHeader := range files {
+		data, mimeType, message := readReceipt(fileHeader)
+		if message != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+		contents[i] = data
+		mimeTypes[i] = mimeType
+	}
+
+	receipts, err := uploadReceipts(ctx, files, contents, mimeTypes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload receipt: " + err.Error()})
+		return
+	}
+
+	// Batas jumlah struk diperiksa ulang saat update agar upload bersamaan tidak melebihinya
+	err = collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":  objectID,
+			"$and": []bson.M{access},
+			"$expr": bson.M{"$lte": bson.A{
+				bson.M{"$size": bson.M{"$ifNull": bson.A{"$receipts", bson.A{}}}},
+				maxReceiptsPerTransaction - len(receipts),
+			}},
+		},
+		bson.M{
+			"$push": bson.M{"receipts": bson.M{"$each": receipts}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&transaction)
+	if err == mongo.ErrNoDocuments {
+		deleteReceiptFiles(ctx, receipts)
+		c.JSON(http.StatusConflict, gin.H{"error": "Maksimal 5 struk per transaksi"})
+		return
+	}
+	if err != nil {
+		deleteReceiptFiles(ctx, receipts)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save receipts"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Struk berhasil dilampirkan",
+		"receipts":    receipts,
+		"transaction": transaction,
+	})
+}
+
+// DeleteTransactionReceipt menghapus satu struk dari transaksi dan storage
+func DeleteTransactionReceipt(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+	// fileId memakai wildcard karena file ID storage lokal dan S3 berupa path
+	fileID := strings.TrimPrefix(c.Param("fileId"), "/")
+
+	collection := config.GetCollection("transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	access, err := transactionAccessFilter(ctx, userObjectID, "editor")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete receipt"})
+		return
+	}
+
+	filter := bson.M{"_id": objectID, "$and": []bson.M{access}, "receipts.file_id": fileID}
+	count, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete receipt"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Struk tidak ditemukan"})
+		return
+	}
+
+	if err := utils.GetStorage().Delete(ctx, fileID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete receipt file: " + err.Error()})
+		return
+	}
+
+	var transaction models.Transaction
+	err = collection.FindOneAndUpdate(ctx, filter,
+		bson.M{
+			"$pull": bson.M{"receipts": bson.M{"file_id": fileID}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&transaction)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete receipt"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Struk berhasil dihapus",
+		"transaction": transaction,
+	})
+}
@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+	"DompetKu/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pngHeader cukup untuk dikenali http.DetectContentType sebagai image/png
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+type receiptFile struct {
+	name string
+	data []byte
+}
+
+// useLocalStorage mengganti storage dengan LocalStorage di direktori sementara
+func useLocalStorage(t *testing.T) string {
+	t.Helper()
+	previous := utils.GetStorage()
+	dir := t.TempDir()
+	utils.SetStorage(utils.NewLocalStorage(dir, ""))
+	t.Cleanup(func() { utils.SetStorage(previous) })
+	return dir
+}
+
+// storedFiles mengembalikan path semua file di dir
+func storedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("walk %s: %v", dir, err)
+	}
+	return files
+}
+
+func multipartReceipts(t *testing.T, files []receiptFile) (*bytes.Buffer, string) {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, f := range files {
+		part, err := writer.CreateFormFile("receipts", f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(f.data)
+	}
+	writer.Close()
+	return body, writer.FormDataContentType()
+}
+
+func receiptFileHeaders(t *testing.T, files []receiptFile) []*multipart.FileHeader {
+	t.Helper()
+	body, contentType := multipartReceipts(t, files)
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", contentType)
+	if err := req.ParseMultipartForm(32 << 20); err != nil {
+		t.Fatal(err)
+	}
+	return req.MultipartForm.File["receipts"]
+}
+
+// uploadReceiptsRequest menjalankan UploadTransactionReceipts sebagai userID
+func uploadReceiptsRequest(t *testing.T, userID, transactionID primitive.ObjectID, files []receiptFile) (int, gin.H) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	body, contentType := multipartReceipts(t, files)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/api/transactions/"+transactionID.Hex()+"/receipts", body)
+	c.Request.Header.Set("Content-Type", contentType)
+	c.Params = gin.Params{{Key: "id", Value: transactionID.Hex()}}
+	c.Set("userID", userID.Hex())
+
+	UploadTransactionReceipts(c)
+
+	var response gin.H
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func TestReadReceiptRejectsNonImages(t *testing.T) {
+	headers := receiptFileHeaders(t, []receiptFile{
+		{"struk.jpg", []byte("ini bukan gambar, hanya teks")},
+		{"struk.png", []byte("<html><body>struk</body></html>")},
+		{"struk.pdf", []byte("%PDF-1.4 struk")},
+	})
+
+	for _, fh := range headers {
+		_, _, message := readReceipt(fh)
+		if !strings.Contains(message, "bukan gambar") {
+			t.Errorf("readReceipt(%s) message = %q, want MIME rejection", fh.Filename, message)
+		}
+	}
+}
+
+func TestReadReceiptDetectsImageType(t *testing.T) {
+	headers := receiptFileHeaders(t, []receiptFile{
+		// Nama file dan Content-Type dari client diabaikan, tipe diambil dari isi file
+		{"struk.jpg", append(append([]byte{}, pngHeader...), make([]byte, 64)...)},
+	})
+
+	data, mimeType, message := readReceipt(headers[0])
+	if message != "" {
+		t.Fatalf("readReceipt message = %q", message)
+	}
+	if mimeType != "image/png" {
+		t.Errorf("mimeType = %q, want image/png", mimeType)
+	}
+	if len(data) != len(pngHeader)+64 {
+		t.Errorf("len(data) = %d", len(data))
+	}
+}
+
+func TestReadReceiptSizeLimit(t *testing.T) {
+	atLimit := append(append([]byte{}, pngHeader...), make([]byte, maxReceiptSize-len(pngHeader))...)
+	overLimit := append(append([]byte{}, atLimit...), 0)
+
+	headers := receiptFileHeaders(t, []receiptFile{
+		{"pas.png", atLimit},
+		{"besar.png", overLimit},
+	})
+
+	if _, _, message := readReceipt(headers[0]); message != "" {
+		t.Errorf("file of exactly 5 MB rejected: %q", message)
+	}
+	if _, _, message := readReceipt(headers[1]); !strings.Contains(message, "melebihi 5 MB") {
+		t.Errorf("file over 5 MB message = %q, want size rejection", message)
+	}
+}
+
+func TestUploadTransactionReceiptsTooManyFiles(t *testing.T) {
+	dir := useLocalStorage(t)
+
+	files := make([]receiptFile, maxReceiptsPerTransaction+1)
+	for i := range files {
+		files[i] = receiptFile{"struk.png", pngHeader}
+	}
+
+	// Jumlah file diperiksa sebelum transaksi diambil dari database
+	code, response := uploadReceiptsRequest(t, primitive.NewObjectID(), primitive.NewObjectID(), files)
+	if code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 (%v)", code, response)
+	}
+	if files := storedFiles(t, dir); len(files) != 0 {
+		t.Errorf("files were stored: %v", files)
+	}
+}
+
+// failingStorage gagal pada upload ke-failAt (mulai dari 1)
+type failingStorage struct {
+	utils.Storage
+	failAt  int
+	uploads int
+}
+
+func (s *failingStorage) Upload(ctx context.Context, file io.Reader, filename string, folder string) (*utils.StoredFile, error) {
+	s.uploads++
+	if s.uploads == s.failAt {
+		return nil, errors.New("storage unavailable")
+	}
+	return s.Storage.Upload(ctx, file, filename, folder)
+}
+
+func TestUploadReceiptsRollsBackOnFailure(t *testing.T) {
+	dir := useLocalStorage(t)
+	storage := &failingStorage{Storage: utils.GetStorage(), failAt: 3}
+	utils.SetStorage(storage)
+
+	files := []receiptFile{{"a.png", pngHeader}, {"b.png", pngHeader}, {"c.png", pngHeader}}
+	headers := receiptFileHeaders(t, files)
+	contents := [][]byte{pngHeader, pngHeader, pngHeader}
+	mimeTypes := []string{"image/png", "image/png", "image/png"}
+
+	receipts, err := uploadReceipts(context.Background(), headers, contents, mimeTypes)
+	if err == nil {
+		t.Fatalf("uploadReceipts succeeded with %d receipts, want error", len(receipts))
+	}
+	if storage.uploads != 3 {
+		t.Errorf("uploads = %d, want 3", storage.uploads)
+	}
+	if files := storedFiles(t, dir); len(files) != 0 {
+		t.Errorf("uploaded files were not removed: %v", files)
+	}
+}
+
+func TestUploadReceiptsStoresFiles(t *testing.T) {
+	dir := useLocalStorage(t)
+
+	headers := receiptFileHeaders(t, []receiptFile{{"struk.PNG", pngHeader}})
+	receipts, err := uploadReceipts(context.Background(), headers, [][]byte{pngHeader}, []string{"image/png"})
+	if err != nil {
+		t.Fatalf("uploadReceipts: %v", err)
+	}
+	if len(receipts) != 1 {
+		t.Fatalf("got %d receipts", len(receipts))
+	}
+
+	r := receipts[0]
+	if !strings.HasPrefix(r.FileID, "Dompetku/receipts/receipt_") || !strings.HasSuffix(r.FileID, ".png") {
+		t.Errorf("FileID = %q", r.FileID)
+	}
+	if r.URL != utils.LocalStorageRoute+"/"+r.FileID {
+		t.Errorf("URL = %q", r.URL)
+	}
+	if r.Nama != "struk.PNG" || r.MimeType != "image/png" || r.Size != int64(len(pngHeader)) {
+		t.Errorf("receipt = %+v", r)
+	}
+	if files := storedFiles(t, dir); len(files) != 1 {
+		t.Errorf("stored files = %v", files)
+	}
+
+	deleteReceiptFiles(context.Background(), receipts)
+	if files := storedFiles(t, dir); len(files) != 0 {
+		t.Errorf("files left after deleteReceiptFiles: %v", files)
+	}
+}
+
+func TestUploadTransactionReceiptsCap(t *testing.T) {
+	ctx := setupTestDB(t)
+	dir := useLocalStorage(t)
+
+	userID := primitive.NewObjectID()
+	existing := make([]models.Receipt, maxReceiptsPerTransaction-1)
+	for i := range existing {
+		existing[i] = models.Receipt{FileID: primitive.NewObjectID().Hex(), MimeType: "image/png", UploadedAt: time.Now()}
+	}
+	transaction := models.Transaction{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Tipe:      "pengeluaran",
+		Nominal:   models.Money(25_000_00),
+		Kategori:  "Makanan",
+		Tanggal:   today(time.Now()),
+		Receipts:  existing,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if _, err := config.GetCollection("transactions").InsertOne(ctx, transaction); err != nil {
+		t.Fatalf("insert transaction: %v", err)
+	}
+
+	// Sisa satu slot, dua file ditolak tanpa ada yang diupload
+	code, response := uploadReceiptsRequest(t, userID, transaction.ID, []receiptFile{{"a.png", pngHeader}, {"b.png", pngHeader}})
+	if code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 (%v)", code, response)
+	}
+	if files := storedFiles(t, dir); len(files) != 0 {
+		t.Errorf("files were stored: %v", files)
+	}
+
+	// Satu file masih muat
+	code, response = uploadReceiptsRequest(t, userID, transaction.ID, []receiptFile{{"a.png", pngHeader}})
+	if code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201 (%v)", code, response)
+	}
+
+	var saved models.Transaction
+	if err := config.GetCollection("transactions").FindOne(ctx, bson.M{"_id": transaction.ID}).Decode(&saved); err != nil {
+		t.Fatalf("find transaction: %v", err)
+	}
+	if len(saved.Receipts) != maxReceiptsPerTransaction {
+		t.Errorf("got %d receipts, want %d", len(saved.Receipts), maxReceiptsPerTransaction)
+	}
+	if files := storedFiles(t, dir); len(files) != 1 {
+		t.Errorf("stored files = %v", files)
+	}
+}
@@ -0,0 +1,574 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// today returns the current date at midnight UTC, matching how tanggal is stored
+func today(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// EnsureRecurringIndexes membuat unique index (recurring_id, tanggal) sehingga
+// satu jadwal tidak pernah menghasilkan dua transaksi pada tanggal yang sama
+func EnsureRecurringIndexes(ctx context.Context) error {
+	_, err := config.GetCollection("transactions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "recurring_id", Value: 1}, {Key: "tanggal", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"recurring_id": bson.M{"$exists": true}}),
+	})
+	return err
+}
+
+// ProcessDueRecurringTransactions membuat transaksi untuk setiap jadwal
+// berulang yang sudah jatuh tempo, termasuk jadwal yang terlewat. Aman
+// dijalankan berulang kali atau bersamaan: transaksi di-upsert per
+// (recurring_id, tanggal) dan next_run hanya dimajukan jika belum diubah
+// proses lain. Jadwal yang gagal tidak menghentikan jadwal lain, errornya
+// dikumpulkan. Mengembalikan jumlah transaksi yang baru dibuat.
+func ProcessDueRecurringTransactions(ctx context.Context, filter bson.M, now time.Time) (int, error) {
+	dueFilter := bson.M{"is_paused": false, "next_run": bson.M{"$lte": today(now)}}
+	for k, v := range filter {
+		dueFilter[k] = v
+	}
+
+	cursor, err := config.GetCollection("recurring_transactions").Find(ctx, dueFilter)
+	if err != nil {
+		return 0, err
+	}
+
+	var schedules []models.RecurringTransaction
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return 0, err
+	}
+
+	created := 0
+	var errs []error
+	for _, r := range schedules {
+		n, err := processRecurringSchedule(ctx, r, now)
+		created += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recurring %s: %w", r.ID.Hex(), err))
+		}
+	}
+
+	return created, errors.Join(errs...)
+}
+
+// processRecurringSchedule membuat transaksi yang jatuh tempo untuk satu jadwal
+func processRecurringSchedule(ctx context.Context, r models.RecurringTransaction, now time.Time) (int, error) {
+	collection := config.GetCollection("recurring_transactions")
+	transactionCollection := config.GetCollection("transactions")
+
+	currency, err := accountCurrency(ctx, r.UserID, r.AccountID)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for !r.NextRun.After(today(now)) && !r.IsEnded(r.NextRun) {
+		transaction := models.Transaction{
+			ID:          primitive.NewObjectID(),
+			UserID:      r.UserID,
+			AccountID:   r.AccountID,
+			Tipe:        r.Tipe,
+			Nominal:     r.Nominal,
+			Currency:    currency,
+			Kategori:    r.Kategori,
+			Catatan:     r.Catatan,
+			Tanggal:     r.NextRun,
+			RecurringID: &r.ID,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+
+		result, err := transactionCollection.UpdateOne(ctx,
+			bson.M{"recurring_id": r.ID, "tanggal": r.NextRun},
+			bson.M{"$setOnInsert": transaction},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return created, err
+		}
+		if err == nil && result.UpsertedCount > 0 {
+			created++
+			checkBudgetAlerts(ctx, r.UserID, transaction, nil)
+			applyGoalRules(ctx, transaction)
+		}
+
+		next := r.NextOccurrence(r.NextRun)
+		advance, err := collection.UpdateOne(ctx,
+			bson.M{"_id": r.ID, "next_run": r.NextRun},
+			bson.M{"$set": bson.M{"next_run": next, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			return created, err
+		}
+		if advance.ModifiedCount == 0 {
+			// Jadwal sudah dimajukan oleh proses lain
+			break
+		}
+		r.NextRun = next
+	}
+
+	return created, nil
+}
+
+func CreateRecurringTransaction(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.CreateRecurringTransactionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tanggalMulai, err := time.Parse("2006-01-02", input.TanggalMulai)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format tanggal_mulai tidak valid. Gunakan format YYYY-MM-DD"})
+		return
+	}
+
+	var tanggalSelesai *time.Time
+	if input.TanggalSelesai != "" {
+		parsed, err := time.Parse("2006-01-02", input.TanggalSelesai)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format tanggal_selesai tidak valid. Gunakan format YYYY-MM-DD"})
+			return
+		}
+		if parsed.Before(tanggalMulai) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tanggal_selesai tidak boleh sebelum tanggal_mulai"})
+			return
+		}
+		tanggalSelesai = &parsed
+	}
+
+	collection := config.GetCollection("recurring_transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	valid, allowed, err := validateUserCategory(ctx, objectID, input.Tipe, input.Kategori)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate category"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":              "Kategori tidak valid",
+			"allowed_categories": allowed,
+		})
+		return
+	}
+
+	var accountID *primitive.ObjectID
+	if input.AccountID != "" {
+		account, err := findUserAccount(ctx, objectID, input.AccountID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak ditemukan"})
+			return
+		}
+		accountID = &account.ID
+	}
+
+	recurring := models.RecurringTransaction{
+		ID:             primitive.NewObjectID(),
+		UserID:         objectID,
+		AccountID:      accountID,
+		Tipe:           input.Tipe,
+		Nominal:        input.Nominal,
+		Kategori:       input.Kategori,
+		Catatan:        input.Catatan,
+		Frekuensi:      input.Frekuensi,
+		Hari:           input.Hari,
+		TanggalMulai:   tanggalMulai,
+		TanggalSelesai: tanggalSelesai,
+		IsPaused:       false,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	if recurring.Frekuensi != "bulanan" {
+		recurring.Hari = 0
+	}
+	recurring.NextRun = recurring.NextOccurrence(tanggalMulai.Add(-time.Nanosecond))
+
+	_, err = collection.InsertOne(ctx, recurring)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recurring transaction"})
+		return
+	}
+
+	// Jadwal yang dimulai di masa lalu langsung dibuatkan transaksinya
+	created, _ := ProcessDueRecurringTransactions(ctx, bson.M{"_id": recurring.ID}, time.Now())
+	collection.FindOne(ctx, bson.M{"_id": recurring.ID}).Decode(&recurring)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":              "Transaksi berulang berhasil dibuat",
+		"recurring":            recurring,
+		"transactions_created": created,
+	})
+}
+
+func GetRecurringTransactions(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	collection := config.GetCollection("recurring_transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "next_run", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"user_id": objectID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recurring transactions"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var recurrings []models.RecurringTransaction
+	if err := cursor.All(ctx, &recurrings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode recurring transactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recurring": recurrings,
+		"count":     len(recurrings),
+	})
+}
+
+func GetRecurringTransactionByID(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	recurringID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(recurringID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurring transaction ID"})
+		return
+	}
+
+	collection := config.GetCollection("recurring_transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var recurring models.RecurringTransaction
+	err = collection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&recurring)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaksi berulang tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recurring": recurring})
+}
+
+func UpdateRecurringTransaction(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	recurringID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(recurringID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurring transaction ID"})
+		return
+	}
+
+	var input models.UpdateRecurringTransactionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection := config.GetCollection("recurring_transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Check if recurring transaction exists and belongs to user
+	var existing models.RecurringTransaction
+	err = collection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&existing)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaksi berulang tidak ditemukan"})
+		return
+	}
+
+	update := bson.M{"updated_at": time.Now()}
+
+	if input.Nominal > 0 {
+		update["nominal"] = input.Nominal
+	}
+
+	if input.Kategori != "" {
+		valid, allowed, err := validateUserCategory(ctx, userObjectID, existing.Tipe, input.Kategori)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate category"})
+			return
+		}
+		if !valid {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":              "Kategori tidak valid",
+				"allowed_categories": allowed,
+			})
+			return
+		}
+		update["kategori"] = input.Kategori
+	}
+
+	if input.Catatan != "" {
+		update["catatan"] = input.Catatan
+	}
+
+	if input.AccountID != "" {
+		account, err := findUserAccount(ctx, userObjectID, input.AccountID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak ditemukan"})
+			return
+		}
+		update["account_id"] = account.ID
+	}
+
+	if input.TanggalSelesai != "" {
+		tanggalSelesai, err := time.Parse("2006-01-02", input.TanggalSelesai)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Format tanggal_selesai tidak valid"})
+			return
+		}
+		if tanggalSelesai.Before(existing.TanggalMulai) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tanggal_selesai tidak boleh sebelum tanggal_mulai"})
+			return
+		}
+		update["tanggal_selesai"] = tanggalSelesai
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": update})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update recurring transaction"})
+		return
+	}
+
+	// Get updated recurring transaction
+	var recurring models.RecurringTransaction
+	collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&recurring)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Transaksi berulang berhasil diperbarui",
+		"recurring": recurring,
+	})
+}
+
+func DeleteRecurringTransaction(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	recurringID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(recurringID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurring transaction ID"})
+		return
+	}
+
+	collection := config.GetCollection("recurring_transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Transaksi yang sudah dibuat tetap disimpan
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete recurring transaction"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaksi berulang tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Transaksi berulang berhasil dihapus"})
+}
+
+// PauseRecurringTransaction menghentikan sementara pembuatan transaksi
+func PauseRecurringTransaction(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	recurringID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(recurringID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurring transaction ID"})
+		return
+	}
+
+	collection := config.GetCollection("recurring_transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var recurring models.RecurringTransaction
+	err = collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": objectID, "user_id": userObjectID},
+		bson.M{"$set": bson.M{"is_paused": true, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&recurring)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaksi berulang tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Transaksi berulang dijeda",
+		"recurring": recurring,
+	})
+}
+
+// ResumeRecurringTransaction melanjutkan jadwal mulai tanggal jatuh tempo
+// berikutnya. Jadwal yang terlewat selama dijeda tidak dibuat.
+func ResumeRecurringTransaction(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	recurringID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(recurringID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurring transaction ID"})
+		return
+	}
+
+	collection := config.GetCollection("recurring_transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var recurring models.RecurringTransaction
+	err = collection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&recurring)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaksi berulang tidak ditemukan"})
+		return
+	}
+
+	nextRun := recurring.NextRun
+	if nextRun.Before(today(time.Now())) {
+		nextRun = recurring.NextOccurrence(today(time.Now()).Add(-time.Nanosecond))
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"is_paused":  false,
+			"next_run":   nextRun,
+			"updated_at": time.Now(),
+		},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume recurring transaction"})
+		return
+	}
+
+	created, _ := ProcessDueRecurringTransactions(ctx, bson.M{"_id": objectID}, time.Now())
+	collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&recurring)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "Transaksi berulang dilanjutkan",
+		"recurring":            recurring,
+		"transactions_created": created,
+	})
+}
+
+// SkipRecurringTransaction melewati satu jadwal berikutnya tanpa membuat transaksi
+func SkipRecurringTransaction(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	recurringID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(recurringID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurring transaction ID"})
+		return
+	}
+
+	collection := config.GetCollection("recurring_transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var recurring models.RecurringTransaction
+	err = collection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&recurring)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaksi berulang tidak ditemukan"})
+		return
+	}
+
+	if recurring.IsEnded(recurring.NextRun) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Transaksi berulang sudah berakhir"})
+		return
+	}
+
+	skipped := recurring.NextRun
+	next := recurring.NextOccurrence(skipped)
+
+	// Hanya dimajukan jika next_run belum diubah oleh scheduler
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": objectID, "next_run": skipped},
+		bson.M{"$set": bson.M{"next_run": next, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to skip recurring transaction"})
+		return
+	}
+	if result.ModifiedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Jadwal baru saja diproses, silakan coba lagi"})
+		return
+	}
+
+	recurring.NextRun = next
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Jadwal berhasil dilewati",
+		"skipped_date": skipped,
+		"recurring":    recurring,
+	})
+}
+
+// RunRecurringTransactions memproses jadwal milik user yang sudah jatuh tempo.
+// Berguna di lingkungan serverless yang tidak menjalankan scheduler.
+func RunRecurringTransactions(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	created, err := ProcessDueRecurringTransactions(ctx, bson.M{"user_id": objectID}, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":                "Sebagian transaksi berulang gagal diproses",
+			"transactions_created": created,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "Transaksi berulang berhasil diproses",
+		"transactions_created": created,
+	})
+}
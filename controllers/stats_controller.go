@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"net/http"
+	"sort"
 	"time"
 
 	"DompetKu/config"
@@ -25,12 +26,29 @@ func GetSummary(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Aggregate untuk menghitung total pemasukan dan pengeluaran
+	rates, err := loadRateTable(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+
+	// Total pemasukan dan pengeluaran memakai transaksi yang sama dengan saldo,
+	// termasuk transaksi anggota lain pada akun bersama
+	accountIDs, err := accessibleAccountIDs(ctx, objectID, "viewer")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get summary"})
+		return
+	}
+
+	// Aggregate untuk menghitung total pemasukan dan pengeluaran (transfer tidak dihitung)
 	pipeline := []bson.M{
-		{"$match": bson.M{"user_id": objectID}},
+		{"$match": bson.M{
+			"tipe": bson.M{"$in": []string{"pemasukan", "pengeluaran"}},
+			"$and": []bson.M{balanceFilter(objectID, accountIDs)},
+		}},
 		{"$group": bson.M{
-			"_id":   "$tipe",
-			"total": bson.M{"$sum": "$nominal"},
+			"_id":   withCurrencyKey(bson.M{"tipe": "$tipe"}, rates.base),
+			"total": sumMoney("$nominal"),
 		}},
 	}
 
@@ -41,31 +59,78 @@ func GetSummary(c *gin.Context) {
 	}
 	defer cursor.Close(ctx)
 
-	var results []bson.M
+	var results []struct {
+		ID struct {
+			Tipe          string `bson:"tipe"`
+			currencyGroup `bson:",inline"`
+		} `bson:"_id"`
+		Total models.Money `bson:"total"`
+	}
 	if err := cursor.All(ctx, &results); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode summary"})
 		return
 	}
 
-	var totalPemasukan, totalPengeluaran float64
+	// Total dikonversi ke mata uang dasar dengan kurs pada tanggal transaksi
+	var totalPemasukan, totalPengeluaran models.Money
 	for _, r := range results {
-		if r["_id"] == "pemasukan" {
-			totalPemasukan = r["total"].(float64)
-		} else if r["_id"] == "pengeluaran" {
-			totalPengeluaran = r["total"].(float64)
+		total := rates.convert(r.Total, r.ID.Currency, r.ID.Tanggal)
+		if r.ID.Tipe == "pemasukan" {
+			totalPemasukan += total
+		} else if r.ID.Tipe == "pengeluaran" {
+			totalPengeluaran += total
 		}
 	}
 
-	saldo := totalPemasukan - totalPengeluaran
+	// Saldo per akun, termasuk saldo awal masing-masing akun
+	accounts, saldoTanpaAkun, err := getAccountBalances(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get account balances"})
+		return
+	}
+
+	// Saldo dalam mata uang lain dikonversi dengan kurs terbaru
+	now := time.Now()
+	var tanpaAkun models.Money
+	for currency, total := range saldoTanpaAkun {
+		tanpaAkun += rates.convert(total, currency, now)
+	}
+	saldo := tanpaAkun
+	for _, a := range accounts {
+		saldo += rates.convert(a.Saldo, a.Currency, now)
+	}
+
+	// Sisa utang dan piutang yang belum lunas ditampilkan di samping saldo
+	totalUtang, totalPiutang, err := getDebtTotals(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get debt totals"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"saldo":            saldo,
-		"total_pemasukan":  totalPemasukan,
+		"saldo":             saldo,
+		"total_utang":       totalUtang,
+		"total_piutang":     totalPiutang,
+		"total_pemasukan":   totalPemasukan,
 		"total_pengeluaran": totalPengeluaran,
+		"accounts":          accounts,
+		"saldo_tanpa_akun":  tanpaAkun,
+		"base_currency":     rates.base,
+		"missing_rates":     rates.missingRates(),
 	})
 }
 
 func GetExpenseByCategory(c *gin.Context) {
+	getCategoryBreakdown(c, "pengeluaran")
+}
+
+// GetIncomeByCategory returns pemasukan per sumber (kategori), matching GetExpenseByCategory
+func GetIncomeByCategory(c *gin.Context) {
+	getCategoryBreakdown(c, "pemasukan")
+}
+
+// getCategoryBreakdown aggregates transactions of one tipe per kategori for pie charts
+func getCategoryBreakdown(c *gin.Context, tipe string) {
 	userID, _ := c.Get("userID")
 	objectID, err := primitive.ObjectIDFromHex(userID.(string))
 	if err != nil {
@@ -77,84 +142,179 @@ func GetExpenseByCategory(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Aggregate pengeluaran per kategori
+	rates, err := loadRateTable(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+
+	// Aggregate per kategori, split dihitung ke kategorinya masing-masing
 	pipeline := []bson.M{
 		{"$match": bson.M{
 			"user_id": objectID,
-			"tipe":    "pengeluaran",
+			"tipe":    tipe,
 		}},
-		{"$group": bson.M{
-			"_id":   "$kategori",
-			"total": bson.M{"$sum": "$nominal"},
+	}
+	pipeline = append(pipeline, splitLineStages()...)
+	pipeline = append(pipeline,
+		bson.M{"$group": bson.M{
+			"_id":   withCurrencyKey(bson.M{"kategori": "$lines.kategori"}, rates.base),
+			"total": sumMoney("$lines.nominal"),
 			"count": bson.M{"$sum": 1},
 		}},
-		{"$sort": bson.M{"total": -1}},
-	}
+	)
 
 	cursor, err := collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get expense by category"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get " + tipe + " by category"})
 		return
 	}
 	defer cursor.Close(ctx)
 
-	var results []bson.M
+	var results []struct {
+		ID struct {
+			Kategori      string `bson:"kategori"`
+			currencyGroup `bson:",inline"`
+		} `bson:"_id"`
+		Total models.Money `bson:"total"`
+		Count int32        `bson:"count"`
+	}
 	if err := cursor.All(ctx, &results); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode results"})
 		return
 	}
 
-	// Format untuk pie chart
-	type CategoryData struct {
-		Kategori string  `json:"kategori"`
-		Total    float64 `json:"total"`
-		Count    int32   `json:"count"`
+	// Format untuk pie chart, nominal dikonversi ke mata uang dasar per kategori
+	var rows []CategoryBreakdown
+	index := make(map[string]int)
+	for _, r := range results {
+		i, ok := index[r.ID.Kategori]
+		if !ok {
+			i = len(rows)
+			index[r.ID.Kategori] = i
+			rows = append(rows, CategoryBreakdown{Kategori: r.ID.Kategori})
+		}
+		rows[i].Total += rates.convert(r.Total, r.ID.Currency, r.ID.Tanggal)
+		rows[i].Count += r.Count
 	}
 
-	var categories []CategoryData
-	var grandTotal float64
+	parents, err := getCategoryParents(ctx, objectID, tipe)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
 
-	for _, r := range results {
-		kategori := ""
-		if r["_id"] != nil {
-			kategori = r["_id"].(string)
+	categories, grandTotal := rollUpCategories(rows, parents)
+
+	// Drill-down untuk satu kategori induk, persentase dihitung terhadap total induk
+	if parent := c.Query("parent"); parent != "" {
+		subcategories := []CategoryBreakdown{}
+		var parentTotal models.Money
+		for _, cat := range categories {
+			if cat.Kategori == parent {
+				parentTotal = cat.Total
+				subcategories = cat.Subcategories
+				if len(subcategories) == 0 {
+					cat.Percentage = 100
+					subcategories = []CategoryBreakdown{cat}
+				}
+				break
+			}
 		}
-		total := r["total"].(float64)
-		count := r["count"].(int32)
 
-		categories = append(categories, CategoryData{
-			Kategori: kategori,
-			Total:    total,
-			Count:    count,
+		c.JSON(http.StatusOK, gin.H{
+			"parent":        parent,
+			"categories":    subcategories,
+			"grand_total":   parentTotal,
+			"base_currency": rates.base,
+			"missing_rates": rates.missingRates(),
 		})
-		grandTotal += total
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories":    categories,
+		"grand_total":   grandTotal,
+		"base_currency": rates.base,
+		"missing_rates": rates.missingRates(),
+	})
+}
+
+// splitLineStages memecah setiap transaksi menjadi baris "lines" berisi
+// kategori dan nominal: satu baris per split untuk transaksi split, atau satu
+// baris berisi kategori dan nominal transaksi itu sendiri
+func splitLineStages() []bson.M {
+	return []bson.M{
+		{"$addFields": bson.M{"lines": bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$splits", bson.A{}}}}, 0}},
+			"$splits",
+			bson.A{bson.M{"kategori": "$kategori", "nominal": "$nominal"}},
+		}}}},
+		{"$unwind": "$lines"},
 	}
+}
 
-	// Tambahkan persentase
-	type CategoryWithPercentage struct {
-		Kategori   string  `json:"kategori"`
-		Total      float64 `json:"total"`
-		Count      int32   `json:"count"`
-		Percentage float64 `json:"percentage"`
+// CategoryBreakdown adalah total transaksi satu kategori beserta rincian subkategorinya
+type CategoryBreakdown struct {
+	Kategori      string              `json:"kategori"`
+	Total         models.Money        `json:"total"`
+	Count         int32               `json:"count"`
+	Percentage    float64             `json:"percentage"`
+	Subcategories []CategoryBreakdown `json:"subcategories,omitempty"`
+}
+
+// rollUpCategories menjumlahkan total subkategori ke kategori induknya dan
+// menghitung persentase di setiap tingkat. Transaksi yang langsung memakai
+// kategori induk muncul sebagai rincian dengan nama induk itu sendiri.
+func rollUpCategories(rows []CategoryBreakdown, parents map[string]string) ([]CategoryBreakdown, models.Money) {
+	groups := make(map[string]*CategoryBreakdown)
+	var order []string
+
+	for _, row := range rows {
+		parent := row.Kategori
+		if p, ok := parents[row.Kategori]; ok {
+			parent = p
+		}
+
+		group, ok := groups[parent]
+		if !ok {
+			group = &CategoryBreakdown{Kategori: parent}
+			groups[parent] = group
+			order = append(order, parent)
+		}
+		group.Total += row.Total
+		group.Count += row.Count
+		group.Subcategories = append(group.Subcategories, row)
 	}
 
-	var categoriesWithPercentage []CategoryWithPercentage
-	for _, cat := range categories {
-		percentage := 0.0
-		if grandTotal > 0 {
-			percentage = (cat.Total / grandTotal) * 100
+	var categories []CategoryBreakdown
+	var grandTotal models.Money
+	for _, name := range order {
+		group := groups[name]
+		if len(group.Subcategories) == 1 && group.Subcategories[0].Kategori == group.Kategori {
+			// Kategori tanpa subkategori tidak perlu rincian
+			group.Subcategories = nil
+		} else {
+			setCategoryPercentages(group.Subcategories, group.Total)
 		}
-		categoriesWithPercentage = append(categoriesWithPercentage, CategoryWithPercentage{
-			Kategori:   cat.Kategori,
-			Total:      cat.Total,
-			Count:      cat.Count,
-			Percentage: percentage,
-		})
+		categories = append(categories, *group)
+		grandTotal += group.Total
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"categories":  categoriesWithPercentage,
-		"grand_total": grandTotal,
+	setCategoryPercentages(categories, grandTotal)
+	return categories, grandTotal
+}
+
+// setCategoryPercentages mengisi persentase terhadap total dan mengurutkan dari yang terbesar
+func setCategoryPercentages(categories []CategoryBreakdown, total models.Money) {
+	for i := range categories {
+		categories[i].Percentage = 0
+		if total > 0 {
+			categories[i].Percentage = categories[i].Total.Ratio(total) * 100
+		}
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i].Total > categories[j].Total
 	})
 }
 
@@ -170,12 +330,21 @@ func GetIncomeVsExpense(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Aggregate pemasukan vs pengeluaran
+	rates, err := loadRateTable(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+
+	// Aggregate pemasukan vs pengeluaran (transfer tidak dihitung)
 	pipeline := []bson.M{
-		{"$match": bson.M{"user_id": objectID}},
+		{"$match": bson.M{
+			"user_id": objectID,
+			"tipe":    bson.M{"$in": []string{"pemasukan", "pengeluaran"}},
+		}},
 		{"$group": bson.M{
-			"_id":   "$tipe",
-			"total": bson.M{"$sum": "$nominal"},
+			"_id":   withCurrencyKey(bson.M{"tipe": "$tipe"}, rates.base),
+			"total": sumMoney("$nominal"),
 			"count": bson.M{"$sum": 1},
 		}},
 	}
@@ -187,22 +356,30 @@ func GetIncomeVsExpense(c *gin.Context) {
 	}
 	defer cursor.Close(ctx)
 
-	var results []bson.M
+	var results []struct {
+		ID struct {
+			Tipe          string `bson:"tipe"`
+			currencyGroup `bson:",inline"`
+		} `bson:"_id"`
+		Total models.Money `bson:"total"`
+		Count int32        `bson:"count"`
+	}
 	if err := cursor.All(ctx, &results); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode results"})
 		return
 	}
 
-	var pemasukan, pengeluaran float64
+	var pemasukan, pengeluaran models.Money
 	var countPemasukan, countPengeluaran int32
 
 	for _, r := range results {
-		if r["_id"] == "pemasukan" {
-			pemasukan = r["total"].(float64)
-			countPemasukan = r["count"].(int32)
-		} else if r["_id"] == "pengeluaran" {
-			pengeluaran = r["total"].(float64)
-			countPengeluaran = r["count"].(int32)
+		total := rates.convert(r.Total, r.ID.Currency, r.ID.Tanggal)
+		if r.ID.Tipe == "pemasukan" {
+			pemasukan += total
+			countPemasukan += r.Count
+		} else if r.ID.Tipe == "pengeluaran" {
+			pengeluaran += total
+			countPengeluaran += r.Count
 		}
 	}
 
@@ -211,8 +388,8 @@ func GetIncomeVsExpense(c *gin.Context) {
 	pengeluaranPercentage := 0.0
 
 	if grandTotal > 0 {
-		pemasukanPercentage = (pemasukan / grandTotal) * 100
-		pengeluaranPercentage = (pengeluaran / grandTotal) * 100
+		pemasukanPercentage = pemasukan.Ratio(grandTotal) * 100
+		pengeluaranPercentage = pengeluaran.Ratio(grandTotal) * 100
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -230,13 +407,138 @@ func GetIncomeVsExpense(c *gin.Context) {
 				"percentage": pengeluaranPercentage,
 			},
 		},
-		"grand_total": grandTotal,
+		"grand_total":   grandTotal,
+		"base_currency": rates.base,
+		"missing_rates": rates.missingRates(),
 	})
 }
 
-// GetCategories returns all allowed expense categories
-func GetCategories(c *gin.Context) {
+// TagStat adalah total pemasukan dan pengeluaran satu tag
+type TagStat struct {
+	Tag         string       `json:"tag"`
+	Pemasukan   models.Money `json:"pemasukan"`
+	Pengeluaran models.Money `json:"pengeluaran"`
+	Count       int32        `json:"count"`
+}
+
+// parseTanggalRange memvalidasi rentang tanggal start dan end (YYYY-MM-DD,
+// inklusif). Default-nya bulan ini.
+func parseTanggalRange(startQuery, endQuery string) (time.Time, time.Time, error) {
+	_, start, end, _ := parseBulan("")
+	end = end.AddDate(0, 0, -1)
+
+	var err error
+	if startQuery != "" {
+		if start, err = time.Parse("2006-01-02", startQuery); err != nil {
+			return start, end, err
+		}
+	}
+	if endQuery != "" {
+		if end, err = time.Parse("2006-01-02", endQuery); err != nil {
+			return start, end, err
+		}
+	}
+	return start, end, nil
+}
+
+// GetStatsByTag returns pemasukan and pengeluaran totals per tag for a date
+// range. Transaksi dengan beberapa tag dihitung penuh di setiap tagnya.
+func GetStatsByTag(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	start, end, err := parseTanggalRange(c.Query("start"), c.Query("end"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format tanggal tidak valid. Gunakan format YYYY-MM-DD"})
+		return
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tanggal end tidak boleh sebelum start"})
+		return
+	}
+
+	collection := config.GetCollection("transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rates, err := loadRateTable(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"user_id": objectID,
+			"tipe":    bson.M{"$in": []string{"pemasukan", "pengeluaran"}},
+			"tanggal": bson.M{"$gte": start, "$lt": end.AddDate(0, 0, 1)},
+			"tags":    bson.M{"$exists": true, "$ne": bson.A{}},
+		}},
+		{"$unwind": "$tags"},
+		{"$group": bson.M{
+			"_id":   withCurrencyKey(bson.M{"tag": "$tags", "tipe": "$tipe"}, rates.base),
+			"total": sumMoney("$nominal"),
+			"count": bson.M{"$sum": 1},
+		}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stats by tag"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		ID struct {
+			Tag           string `bson:"tag"`
+			Tipe          string `bson:"tipe"`
+			currencyGroup `bson:",inline"`
+		} `bson:"_id"`
+		Total models.Money `bson:"total"`
+		Count int32        `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode results"})
+		return
+	}
+
+	tags := []TagStat{}
+	index := make(map[string]int)
+	for _, r := range results {
+		i, ok := index[r.ID.Tag]
+		if !ok {
+			i = len(tags)
+			index[r.ID.Tag] = i
+			tags = append(tags, TagStat{Tag: r.ID.Tag})
+		}
+
+		total := rates.convert(r.Total, r.ID.Currency, r.ID.Tanggal)
+		if r.ID.Tipe == "pemasukan" {
+			tags[i].Pemasukan += total
+		} else {
+			tags[i].Pengeluaran += total
+		}
+		tags[i].Count += r.Count
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Pengeluaran != tags[j].Pengeluaran {
+			return tags[i].Pengeluaran > tags[j].Pengeluaran
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+
 	c.JSON(http.StatusOK, gin.H{
-		"categories": models.AllowedCategories,
+		"start":         start.Format("2006-01-02"),
+		"end":           end.Format("2006-01-02"),
+		"tags":          tags,
+		"count":         len(tags),
+		"base_currency": rates.base,
+		"missing_rates": rates.missingRates(),
 	})
 }
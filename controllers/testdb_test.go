@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"DompetKu/config"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// setupTestDB menghubungkan config.DB ke database sementara di MONGO_TEST_URI
+// dan menghapusnya setelah test selesai. Test dilewati jika MONGO_TEST_URI
+// tidak diisi. Transaksi MongoDB membutuhkan replica set, misalnya
+// mongodb://localhost:27017/?replicaSet=rs0.
+func setupTestDB(t *testing.T) context.Context {
+	t.Helper()
+
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	t.Cleanup(cancel)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+
+	previous := config.DB
+	db := client.Database(fmt.Sprintf("dompetku_test_%d", time.Now().UnixNano()))
+	config.SetDB(db)
+	t.Cleanup(func() {
+		config.SetDB(previous)
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	return ctx
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"DompetKu/config"
@@ -11,6 +12,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -28,21 +30,6 @@ func CreateTransaction(c *gin.Context) {
 		return
 	}
 
-	// Validate kategori for pengeluaran
-	if input.Tipe == "pengeluaran" {
-		if input.Kategori == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Kategori wajib diisi untuk pengeluaran"})
-			return
-		}
-		if !models.IsValidCategory(input.Kategori) {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":              "Kategori tidak valid",
-				"allowed_categories": models.AllowedCategories,
-			})
-			return
-		}
-	}
-
 	// Parse tanggal
 	tanggal, err := time.Parse("2006-01-02", input.Tanggal)
 	if err != nil {
@@ -54,16 +41,99 @@ func CreateTransaction(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Transaksi split memakai kategori split pertama bila kategori tidak diisi
+	splits, ok := buildTransactionSplits(ctx, c, objectID, input.Tipe, input.Nominal, input.Splits)
+	if !ok {
+		return
+	}
+	if input.Kategori == "" && len(splits) > 0 {
+		input.Kategori = splits[0].Kategori
+	}
+
+	// Validate kategori against the user's own categories for the tipe
+	if input.Kategori == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kategori wajib diisi untuk " + input.Tipe})
+		return
+	}
+	valid, allowed, err := validateUserCategory(ctx, objectID, input.Tipe, input.Kategori)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate category"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":              "Kategori tidak valid",
+			"allowed_categories": allowed,
+		})
+		return
+	}
+
+	// Validate akun jika diisi, mata uang transaksi mengikuti akun bila tidak diisi
+	var accountID *primitive.ObjectID
+	currency := input.Currency
+	if input.AccountID != "" {
+		account, err := findUserAccount(ctx, objectID, input.AccountID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak ditemukan"})
+			return
+		}
+		base, err := getBaseCurrency(ctx, objectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch base currency"})
+			return
+		}
+		if currency == "" {
+			currency = account.Currency
+		} else if !currencyMatchesAccount(account, currency, base) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Mata uang transaksi harus sama dengan mata uang akun (" + account.Currency + ")"})
+			return
+		}
+		accountID = &account.ID
+	}
+
+	// Validate cicilan jika diisi
+	var installmentID *primitive.ObjectID
+	if input.InstallmentID != "" {
+		installmentObjectID, err := primitive.ObjectIDFromHex(input.InstallmentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid installment ID"})
+			return
+		}
+		if input.Tipe != "pengeluaran" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pembayaran cicilan harus berupa pengeluaran"})
+			return
+		}
+		count, err := config.GetCollection("installments").CountDocuments(ctx, bson.M{
+			"_id":     installmentObjectID,
+			"user_id": objectID,
+			"status":  "aktif",
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch installment"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cicilan tidak ditemukan atau sudah lunas"})
+			return
+		}
+		installmentID = &installmentObjectID
+	}
+
 	transaction := models.Transaction{
-		ID:        primitive.NewObjectID(),
-		UserID:    objectID,
-		Tipe:      input.Tipe,
-		Nominal:   input.Nominal,
-		Kategori:  input.Kategori,
-		Catatan:   input.Catatan,
-		Tanggal:   tanggal,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:            primitive.NewObjectID(),
+		UserID:        objectID,
+		AccountID:     accountID,
+		Tipe:          input.Tipe,
+		Nominal:       input.Nominal,
+		Currency:      currency,
+		Kategori:      input.Kategori,
+		Splits:        splits,
+		Catatan:       input.Catatan,
+		Tags:          models.NormalizeTags(input.Tags),
+		Tanggal:       tanggal,
+		InstallmentID: installmentID,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
 	}
 
 	_, err = collection.InsertOne(ctx, transaction)
@@ -72,10 +142,28 @@ func CreateTransaction(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	// Angsuran cicilan yang dibayar oleh transaksi ini
+	installment := applyInstallmentPayment(ctx, &transaction)
+
+	response := gin.H{
 		"message":     "Transaksi berhasil ditambahkan",
 		"transaction": transaction,
-	})
+	}
+	if installment != nil {
+		response["installment"] = installment
+	}
+
+	// Peringatan budget tidak menggagalkan transaksi yang sudah tersimpan
+	if warnings, _ := checkBudgetAlerts(ctx, objectID, transaction, nil); len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+
+	// Aturan tabungan otomatis pada goal (persentase pemasukan, pembulatan pengeluaran)
+	if contributions := applyGoalRules(ctx, transaction); len(contributions) > 0 {
+		response["goal_contributions"] = contributions
+	}
+
+	c.JSON(http.StatusCreated, response)
 }
 
 func GetTransactions(c *gin.Context) {
@@ -93,10 +181,58 @@ func GetTransactions(c *gin.Context) {
 	// Filter by tipe if provided
 	filter := bson.M{"user_id": objectID}
 	tipe := c.Query("tipe")
-	if tipe != "" && (tipe == "pemasukan" || tipe == "pengeluaran") {
+	if tipe != "" && (tipe == "pemasukan" || tipe == "pengeluaran" || tipe == "transfer") {
 		filter["tipe"] = tipe
 	}
 
+	// Filter by akun (termasuk sebagai tujuan transfer) if provided
+	if accountID := c.Query("account_id"); accountID != "" {
+		accountObjectID, err := primitive.ObjectIDFromHex(accountID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+			return
+		}
+		filter["$or"] = []bson.M{
+			{"account_id": accountObjectID},
+			{"to_account_id": accountObjectID},
+		}
+
+		// Pada akun bersama, transaksi semua anggota ikut ditampilkan
+		count, _ := config.GetCollection("accounts").CountDocuments(ctx, withAccess(bson.M{"_id": accountObjectID}, objectID, "viewer"))
+		if count > 0 {
+			delete(filter, "user_id")
+		}
+	}
+
+	// Filter by goal if provided
+	if goalID := c.Query("goal_id"); goalID != "" {
+		goalObjectID, err := primitive.ObjectIDFromHex(goalID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goal ID"})
+			return
+		}
+		filter["goal_id"] = goalObjectID
+
+		// Pada goal bersama, transaksi semua anggota ikut ditampilkan
+		count, _ := config.GetCollection("financial_goals").CountDocuments(ctx, withAccess(bson.M{"_id": goalObjectID}, objectID, "viewer"))
+		if count > 0 {
+			delete(filter, "user_id")
+		}
+	}
+
+	// Filter by tag (dipisah koma): tag_match=any (default) cukup salah satu, all harus semuanya
+	if tags := models.NormalizeTags(strings.Split(c.Query("tags"), ",")); len(tags) > 0 {
+		switch c.DefaultQuery("tag_match", "any") {
+		case "any":
+			filter["tags"] = bson.M{"$in": tags}
+		case "all":
+			filter["tags"] = bson.M{"$all": tags}
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tag_match harus any atau all"})
+			return
+		}
+	}
+
 	// Sort by tanggal descending
 	opts := options.Find().SetSort(bson.D{{Key: "tanggal", Value: -1}})
 
@@ -135,7 +271,13 @@ func GetTransactionByID(c *gin.Context) {
 	defer cancel()
 
 	var transaction models.Transaction
-	err = collection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&transaction)
+	access, err := transactionAccessFilter(ctx, userObjectID, "viewer")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transaction"})
+		return
+	}
+
+	err = collection.FindOne(ctx, bson.M{"_id": objectID, "$and": []bson.M{access}}).Decode(&transaction)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Transaksi tidak ditemukan"})
 		return
@@ -166,13 +308,49 @@ func UpdateTransaction(c *gin.Context) {
 	defer cancel()
 
 	// Check if transaction exists and belongs to user
+	access, err := transactionAccessFilter(ctx, userObjectID, "editor")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transaction"})
+		return
+	}
+
+	// Editor akun bersama boleh mengubah transaksi anggota lain pada akun tersebut
 	var existingTransaction models.Transaction
-	err = collection.FindOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID}).Decode(&existingTransaction)
+	err = collection.FindOne(ctx, bson.M{"_id": objectID, "$and": []bson.M{access}}).Decode(&existingTransaction)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Transaksi tidak ditemukan"})
 		return
 	}
 
+	if existingTransaction.Tipe == "transfer" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer tidak dapat diubah, hapus lalu buat ulang melalui /api/transfers"})
+		return
+	}
+
+	// Nominal transaksi goal harus tetap sama dengan dana yang masuk/keluar dari goal
+	if existingTransaction.GoalID != nil && (input.Tipe != "" || input.Nominal > 0 || input.Kategori != "" || input.Splits != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipe, nominal dan kategori transaksi goal tidak dapat diubah"})
+		return
+	}
+
+	// Begitu juga transaksi pembayaran utang/piutang
+	if existingTransaction.DebtID != nil && (input.Tipe != "" || input.Nominal > 0 || input.Kategori != "" || input.Splits != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipe, nominal dan kategori transaksi utang/piutang tidak dapat diubah"})
+		return
+	}
+
+	// Dan transaksi pembayaran cicilan yang sudah menandai angsuran lunas
+	if existingTransaction.InstallmentID != nil && (input.Tipe != "" || input.Nominal > 0 || input.Kategori != "" || input.Splits != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipe, nominal dan kategori transaksi cicilan tidak dapat diubah"})
+		return
+	}
+
+	// Pembayaran tagihan tetap pengeluaran dengan kategori tagihan, nominalnya boleh diubah
+	if existingTransaction.BillID != nil && (input.Tipe != "" || input.Kategori != "" || input.Splits != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipe dan kategori transaksi pembayaran tagihan tidak dapat diubah"})
+		return
+	}
+
 	// Build update object
 	update := bson.M{"updated_at": time.Now()}
 
@@ -187,30 +365,108 @@ func UpdateTransaction(c *gin.Context) {
 		update["nominal"] = input.Nominal
 	}
 
-	// Validate kategori for pengeluaran
-	if tipe == "pengeluaran" {
-		kategori := input.Kategori
-		if kategori == "" {
-			kategori = existingTransaction.Kategori
+	// Split yang ada divalidasi ulang jika tipe atau nominal berubah
+	nominal := existingTransaction.Nominal
+	if input.Nominal > 0 {
+		nominal = input.Nominal
+	}
+	unset := bson.M{}
+	if input.Splits != nil && len(input.Splits) == 0 {
+		unset["splits"] = ""
+	} else if input.Splits != nil || (len(existingTransaction.Splits) > 0 && (input.Tipe != "" || input.Nominal > 0)) {
+		splitInputs := input.Splits
+		if splitInputs == nil {
+			for _, s := range existingTransaction.Splits {
+				splitInputs = append(splitInputs, models.TransactionSplitInput(s))
+			}
+		}
+		splits, ok := buildTransactionSplits(ctx, c, existingTransaction.UserID, tipe, nominal, splitInputs)
+		if !ok {
+			return
+		}
+		update["splits"] = splits
+	}
+
+	// Validate kategori against the user's categories for the (new) tipe. Kategori
+	// yang tidak diubah tidak divalidasi ulang, misalnya kategori Goals pada
+	// penarikan goal yang bukan kategori pemasukan user.
+	kategori := input.Kategori
+	if kategori == "" {
+		kategori = existingTransaction.Kategori
+	}
+	categoryChanged := kategori != existingTransaction.Kategori || tipe != existingTransaction.Tipe
+	if categoryChanged && kategori != "" {
+		valid, allowed, err := validateUserCategory(ctx, existingTransaction.UserID, tipe, kategori)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate category"})
+			return
 		}
-		if kategori != "" && !models.IsValidCategory(kategori) {
+		if !valid {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":              "Kategori tidak valid",
-				"allowed_categories": models.AllowedCategories,
+				"allowed_categories": allowed,
 			})
 			return
 		}
-		if input.Kategori != "" {
-			update["kategori"] = input.Kategori
-		}
-	} else if tipe == "pemasukan" {
-		update["kategori"] = ""
+	} else if categoryChanged && tipe == "pengeluaran" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kategori wajib diisi untuk pengeluaran"})
+		return
+	}
+	if input.Kategori != "" {
+		update["kategori"] = input.Kategori
 	}
 
 	if input.Catatan != "" {
 		update["catatan"] = input.Catatan
 	}
 
+	if input.Tags != nil {
+		if tags := models.NormalizeTags(input.Tags); len(tags) > 0 {
+			update["tags"] = tags
+		} else {
+			unset["tags"] = ""
+		}
+	}
+
+	// Transaksi berakun harus memakai mata uang akun tersebut
+	base, err := getBaseCurrency(ctx, existingTransaction.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch base currency"})
+		return
+	}
+	currency := existingTransaction.Currency
+	if input.Currency != "" {
+		currency = input.Currency
+		update["currency"] = currency
+	}
+	if input.AccountID != "" {
+		account, err := findUserAccount(ctx, userObjectID, input.AccountID)
+		if err == nil && existingTransaction.UserID != userObjectID {
+			// Transaksi anggota lain hanya boleh dipindah ke akun yang juga bisa diakses pemiliknya
+			_, err = findUserAccount(ctx, existingTransaction.UserID, input.AccountID)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tidak ditemukan"})
+			return
+		}
+		if input.Currency == "" && account.Currency != "" {
+			currency = account.Currency
+			update["currency"] = currency
+		}
+		if !currencyMatchesAccount(account, currency, base) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Mata uang transaksi harus sama dengan mata uang akun (" + account.Currency + ")"})
+			return
+		}
+		update["account_id"] = account.ID
+	} else if input.Currency != "" && existingTransaction.AccountID != nil {
+		var account models.Account
+		err := config.GetCollection("accounts").FindOne(ctx, bson.M{"_id": *existingTransaction.AccountID}).Decode(&account)
+		if err == nil && !currencyMatchesAccount(&account, currency, base) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Mata uang transaksi harus sama dengan mata uang akun (" + account.Currency + ")"})
+			return
+		}
+	}
+
 	if input.Tanggal != "" {
 		tanggal, err := time.Parse("2006-01-02", input.Tanggal)
 		if err != nil {
@@ -220,7 +476,12 @@ func UpdateTransaction(c *gin.Context) {
 		update["tanggal"] = tanggal
 	}
 
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": update})
+	changes := bson.M{"$set": update}
+	if len(unset) > 0 {
+		changes["$unset"] = unset
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, changes)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transaction"})
 		return
@@ -230,10 +491,17 @@ func UpdateTransaction(c *gin.Context) {
 	var transaction models.Transaction
 	collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&transaction)
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message":     "Transaksi berhasil diperbarui",
 		"transaction": transaction,
-	})
+	}
+
+	// Peringatan budget tidak menggagalkan transaksi yang sudah tersimpan
+	if warnings, _ := checkBudgetAlerts(ctx, transaction.UserID, transaction, &existingTransaction); len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 func DeleteTransaction(c *gin.Context) {
@@ -251,16 +519,104 @@ func DeleteTransaction(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	result, err := collection.DeleteOne(ctx, bson.M{"_id": objectID, "user_id": userObjectID})
+	access, err := transactionAccessFilter(ctx, userObjectID, "editor")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete transaction"})
 		return
 	}
 
-	if result.DeletedCount == 0 {
+	// Transaksi goal dan utang/piutang hanya bisa dikoreksi melalui goal atau
+	// utang/piutangnya agar saldonya tetap sesuai
+	var deleted models.Transaction
+	err = collection.FindOneAndDelete(ctx, bson.M{
+		"_id":     objectID,
+		"$and":    []bson.M{access},
+		"goal_id": bson.M{"$exists": false},
+		"debt_id": bson.M{"$exists": false},
+	}).Decode(&deleted)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete transaction"})
+		return
+	}
+
+	if err == mongo.ErrNoDocuments {
+		var linked models.Transaction
+		if collection.FindOne(ctx, bson.M{"_id": objectID, "$and": []bson.M{access}}).Decode(&linked) == nil {
+			if linked.DebtID != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Transaksi ini terhubung dengan utang/piutang dan tidak dapat dihapus"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Transaksi ini terhubung dengan goal, gunakan tarik/tambah dana pada goal untuk mengoreksinya"})
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "Transaksi tidak ditemukan"})
 		return
 	}
 
+	releaseInstallmentPayment(ctx, objectID)
+	deleteReceiptFiles(ctx, deleted.Receipts)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Transaksi berhasil dihapus"})
 }
+
+// currencyMatchesAccount reports whether a transaction in currency may be
+// recorded on account. Mata uang kosong berarti mata uang dasar base.
+func currencyMatchesAccount(account *models.Account, currency, base string) bool {
+	accountCurrency := account.Currency
+	if accountCurrency == "" {
+		accountCurrency = base
+	}
+	if currency == "" {
+		currency = base
+	}
+	return accountCurrency == currency
+}
+
+// buildTransactionSplits memvalidasi rincian split: minimal dua baris,
+// kategori setiap baris valid untuk tipe transaksi dan jumlah nominalnya
+// sama dengan nominal transaksi. Tanpa split mengembalikan nil.
+func buildTransactionSplits(ctx context.Context, c *gin.Context, userID primitive.ObjectID, tipe string, nominal models.Money, inputs []models.TransactionSplitInput) ([]models.TransactionSplit, bool) {
+	if len(inputs) == 0 {
+		return nil, true
+	}
+	if len(inputs) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Split minimal terdiri dari dua kategori"})
+		return nil, false
+	}
+
+	splits := make([]models.TransactionSplit, 0, len(inputs))
+	var total models.Money
+	for _, in := range inputs {
+		if in.Kategori == "" || in.Nominal <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Setiap split wajib memiliki kategori dan nominal lebih dari 0"})
+			return nil, false
+		}
+
+		valid, allowed, err := validateUserCategory(ctx, userID, tipe, in.Kategori)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate category"})
+			return nil, false
+		}
+		if !valid {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":              "Kategori split tidak valid: " + in.Kategori,
+				"allowed_categories": allowed,
+			})
+			return nil, false
+		}
+
+		splits = append(splits, models.TransactionSplit(in))
+		total += in.Nominal
+	}
+
+	if total != nominal {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":       "Jumlah nominal split harus sama dengan nominal transaksi",
+			"nominal":     nominal,
+			"total_split": total,
+		})
+		return nil, false
+	}
+
+	return splits, true
+}
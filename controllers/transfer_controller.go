@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"DompetKu/config"
+	"DompetKu/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateTransfer memindahkan dana antar akun. Transfer disimpan sebagai satu
+// dokumen transaksi bertipe "transfer" sehingga akun asal dan tujuan selalu
+// berubah bersamaan.
+func CreateTransfer(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.CreateTransferInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.FromAccountID == input.ToAccountID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Akun asal dan tujuan tidak boleh sama"})
+		return
+	}
+
+	// Parse tanggal
+	tanggal, err := time.Parse("2006-01-02", input.Tanggal)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format tanggal tidak valid. Gunakan format YYYY-MM-DD"})
+		return
+	}
+
+	collection := config.GetCollection("transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	fromAccount, err := findUserAccount(ctx, objectID, input.FromAccountID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Akun asal tidak ditemukan"})
+		return
+	}
+
+	toAccount, err := findUserAccount(ctx, objectID, input.ToAccountID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Akun tujuan tidak ditemukan"})
+		return
+	}
+
+	// Transfer antar mata uang tidak didukung karena nominalnya hanya satu
+	base, err := getBaseCurrency(ctx, objectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch base currency"})
+		return
+	}
+	if !currencyMatchesAccount(toAccount, fromAccount.Currency, base) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Akun asal dan tujuan harus memiliki mata uang yang sama"})
+		return
+	}
+
+	transfer := models.Transaction{
+		ID:          primitive.NewObjectID(),
+		UserID:      objectID,
+		AccountID:   &fromAccount.ID,
+		ToAccountID: &toAccount.ID,
+		Tipe:        "transfer",
+		Nominal:     input.Nominal,
+		Currency:    fromAccount.Currency,
+		Catatan:     input.Catatan,
+		Tanggal:     tanggal,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	_, err = collection.InsertOne(ctx, transfer)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Transfer berhasil dicatat",
+		"transfer": transfer,
+	})
+}
+
+func GetTransfers(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	collection := config.GetCollection("transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Transfer dari atau ke akun bersama ikut ditampilkan untuk anggotanya
+	access, err := transactionAccessFilter(ctx, objectID, "viewer")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfers"})
+		return
+	}
+	filter := bson.M{"tipe": "transfer", "$and": []bson.M{access}}
+
+	// Filter by akun (asal maupun tujuan) if provided
+	if accountID := c.Query("account_id"); accountID != "" {
+		accountObjectID, err := primitive.ObjectIDFromHex(accountID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+			return
+		}
+		filter["$or"] = []bson.M{
+			{"account_id": accountObjectID},
+			{"to_account_id": accountObjectID},
+		}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "tanggal", Value: -1}})
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfers"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var transfers []models.Transaction
+	if err := cursor.All(ctx, &transfers); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode transfers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transfers": transfers,
+		"count":     len(transfers),
+	})
+}
+
+func DeleteTransfer(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userObjectID, _ := primitive.ObjectIDFromHex(userID.(string))
+
+	transferID := c.Param("id")
+	objectID, err := primitive.ObjectIDFromHex(transferID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer ID"})
+		return
+	}
+
+	collection := config.GetCollection("transactions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	access, err := transactionAccessFilter(ctx, userObjectID, "editor")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete transfer"})
+		return
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objectID, "tipe": "transfer", "$and": []bson.M{access}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete transfer"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transfer tidak ditemukan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Transfer berhasil dihapus"})
+}
@@ -36,11 +36,12 @@ func GetProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"user": gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"nama":       user.Nama,
-			"foto":       user.Foto,
-			"created_at": user.CreatedAt,
+			"id":            user.ID,
+			"username":      user.Username,
+			"nama":          user.Nama,
+			"foto":          user.Foto,
+			"base_currency": user.Currency(),
+			"created_at":    user.CreatedAt,
 		},
 	})
 }
@@ -73,13 +74,13 @@ func UpdateProfile(c *gin.Context) {
 		if err == nil && file != nil {
 			defer file.Close()
 
-			// Upload to ImageKit
-			imageURL, err := utils.UploadToImageKit(file, fileHeader, "Dompetku")
+			// Upload ke storage yang dikonfigurasi
+			stored, err := utils.GetStorage().Upload(ctx, file, utils.UniqueFileName("profile", fileHeader.Filename), "Dompetku")
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image: " + err.Error()})
 				return
 			}
-			update["foto"] = imageURL
+			update["foto"] = stored.URL
 		}
 	} else {
 		// Handle JSON update (backward compatible)
@@ -95,6 +96,13 @@ func UpdateProfile(c *gin.Context) {
 		if input.Foto != "" {
 			update["foto"] = input.Foto
 		}
+		if input.BaseCurrency != "" {
+			if err := changeBaseCurrency(ctx, objectID, input.BaseCurrency); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update base currency"})
+				return
+			}
+			update["base_currency"] = input.BaseCurrency
+		}
 	}
 
 	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": update})
@@ -110,14 +118,33 @@ func UpdateProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Profil berhasil diperbarui",
 		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"nama":     user.Nama,
-			"foto":     user.Foto,
+			"id":            user.ID,
+			"username":      user.Username,
+			"nama":          user.Nama,
+			"foto":          user.Foto,
+			"base_currency": user.Currency(),
 		},
 	})
 }
 
+// changeBaseCurrency menyimpan mata uang dasar lama pada transaksi dan akun
+// user yang belum memiliki mata uang sebelum mata uang dasar diganti, agar
+// nominalnya tidak ikut berpindah mata uang
+func changeBaseCurrency(ctx context.Context, userID primitive.ObjectID, currency string) error {
+	base, err := getBaseCurrency(ctx, userID)
+	if err != nil || base == currency {
+		return err
+	}
+
+	filter := bson.M{"user_id": userID, "currency": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"currency": base}}
+	if _, err := config.GetCollection("transactions").UpdateMany(ctx, filter, update); err != nil {
+		return err
+	}
+	_, err = config.GetCollection("accounts").UpdateMany(ctx, filter, update)
+	return err
+}
+
 func ChangePassword(c *gin.Context) {
 	userID, _ := c.Get("userID")
 	objectID, err := primitive.ObjectIDFromHex(userID.(string))
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"time"
 
 	"DompetKu/config"
+	"DompetKu/controllers"
 	"DompetKu/routes"
+	"DompetKu/scheduler"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -21,6 +25,19 @@ func main() {
 	// Connect to MongoDB
 	config.ConnectDB()
 
+	// Ubah nominal lama (float) menjadi int64 satuan terkecil
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	if err := controllers.MigrateMoneyFields(ctx); err != nil {
+		log.Println("Warning: failed to migrate money fields:", err)
+	}
+	if err := controllers.EnsureIndexes(ctx); err != nil {
+		log.Println("Warning: failed to create indexes:", err)
+	}
+	cancel()
+
+	// Create due recurring transactions every hour
+	scheduler.Start(time.Hour)
+
 	// Setup Gin router
 	router := gin.Default()
 
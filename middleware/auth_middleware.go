@@ -18,42 +18,63 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Check if header starts with "Bearer "
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		claims, errMessage := parseToken(authHeader)
+		if errMessage != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errMessage})
 			c.Abort()
 			return
 		}
 
-		tokenString := parts[1]
-		jwtSecret := os.Getenv("JWT_SECRET")
-		if jwtSecret == "" {
-			jwtSecret = "dompetku-secret-key" // default secret
-		}
+		// Set user ID in context
+		c.Set("userID", claims["user_id"])
+		c.Next()
+	}
+}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
+// OptionalAuthMiddleware sets userID when a valid token is sent, but never
+// rejects the request. Used by public endpoints that personalise their response.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader != "" {
+			if claims, errMessage := parseToken(authHeader); errMessage == "" {
+				c.Set("userID", claims["user_id"])
 			}
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
-			return
 		}
+		c.Next()
+	}
+}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
+// parseToken validates the bearer token and returns its claims, or an error
+// message suitable for the response body
+func parseToken(authHeader string) (jwt.MapClaims, string) {
+	// Check if header starts with "Bearer "
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, "Invalid authorization header format"
+	}
+
+	tokenString := parts[1]
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		jwtSecret = "dompetku-secret-key" // default secret
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
 		}
+		return []byte(jwtSecret), nil
+	})
 
-		// Set user ID in context
-		c.Set("userID", claims["user_id"])
-		c.Next()
+	if err != nil || !token.Valid {
+		return nil, "Invalid or expired token"
 	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, "Invalid token claims"
+	}
+
+	return claims, ""
 }
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Account struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Nama      string             `bson:"nama" json:"nama"`
+	Tipe      string             `bson:"tipe" json:"tipe"` // "tunai", "bank", "e-wallet" atau "lainnya"
+	SaldoAwal Money              `bson:"saldo_awal" json:"saldo_awal"`
+	Currency  string             `bson:"currency,omitempty" json:"currency,omitempty"` // kosong berarti mata uang dasar pemilik
+	Members   []Member           `bson:"members,omitempty" json:"members,omitempty"`   // akun bersama
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type CreateAccountInput struct {
+	Nama      string `json:"nama" binding:"required"`
+	Tipe      string `json:"tipe" binding:"required,oneof=tunai bank e-wallet lainnya"`
+	SaldoAwal Money  `json:"saldo_awal" binding:"gte=0"`
+	Currency  string `json:"currency" binding:"omitempty,iso4217"` // default mata uang dasar user
+}
+
+type UpdateAccountInput struct {
+	Nama      string `json:"nama" binding:"omitempty"`
+	Tipe      string `json:"tipe" binding:"omitempty,oneof=tunai bank e-wallet lainnya"`
+	SaldoAwal *Money `json:"saldo_awal" binding:"omitempty,gte=0"`
+}
+
+// RoleOf returns "owner", "editor", "viewer" or "" for userID
+func (a *Account) RoleOf(userID primitive.ObjectID) string {
+	return roleOf(a.UserID, a.Members, userID)
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bill adalah tagihan bulanan (PLN, BPJS, internet) yang jatuh tempo setiap
+// tanggal DueDay. Pembayaran satu periode dicatat sebagai transaksi
+// pengeluaran dengan bill_id dan bill_periode.
+type Bill struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Nama         string              `bson:"nama" json:"nama"`
+	Kategori     string              `bson:"kategori" json:"kategori"`
+	Amount       Money               `bson:"amount" json:"amount"`
+	DueDay       int                 `bson:"due_day" json:"due_day"` // 1-31, dijatuhkan ke hari terakhir pada bulan yang lebih pendek
+	AccountID    *primitive.ObjectID `bson:"account_id,omitempty" json:"account_id"`
+	Catatan      string              `bson:"catatan" json:"catatan"`
+	StartPeriode string              `bson:"start_periode" json:"start_periode"` // periode pertama yang ditagih, format YYYY-MM
+	IsActive     bool                `bson:"is_active" json:"is_active"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// UpcomingBill adalah satu periode tagihan yang belum dibayar
+type UpcomingBill struct {
+	BillID    primitive.ObjectID `json:"bill_id"`
+	Nama      string             `json:"nama"`
+	Kategori  string             `json:"kategori"`
+	Amount    Money              `json:"amount"`
+	Periode   string             `json:"periode"`
+	DueDate   time.Time          `json:"due_date"`
+	IsOverdue bool               `json:"is_overdue"`
+}
+
+type CreateBillInput struct {
+	Nama         string `json:"nama" binding:"required"`
+	Kategori     string `json:"kategori" binding:"required"`
+	Amount       Money  `json:"amount" binding:"required,gt=0"`
+	DueDay       int    `json:"due_day" binding:"required,min=1,max=31"`
+	AccountID    string `json:"account_id"`
+	Catatan      string `json:"catatan"`
+	StartPeriode string `json:"start_periode"` // format YYYY-MM, default bulan ini
+}
+
+type UpdateBillInput struct {
+	Nama      string  `json:"nama" binding:"omitempty"`
+	Kategori  string  `json:"kategori" binding:"omitempty"`
+	Amount    Money   `json:"amount" binding:"omitempty,gt=0"`
+	DueDay    int     `json:"due_day" binding:"omitempty,min=1,max=31"`
+	AccountID *string `json:"account_id"` // "" untuk melepas akun
+	Catatan   *string `json:"catatan"`
+	IsActive  *bool   `json:"is_active"`
+}
+
+// Nominal boleh berbeda dari amount tagihan, misalnya tagihan PLN yang berubah setiap bulan
+type PayBillInput struct {
+	Periode   string `json:"periode"` // format YYYY-MM, default periode tertua yang belum dibayar
+	Nominal   Money  `json:"nominal" binding:"omitempty,gt=0"`
+	Tanggal   string `json:"tanggal"` // format YYYY-MM-DD, default hari ini
+	AccountID string `json:"account_id"`
+	Catatan   string `json:"catatan"`
+}
+
+// BillPeriodeLayout adalah format periode tagihan (YYYY-MM)
+const BillPeriodeLayout = "2006-01"
+
+// DueDateFor returns the due date of the bill in the month of periode
+func (b *Bill) DueDateFor(periode time.Time) time.Time {
+	first := time.Date(periode.Year(), periode.Month(), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := b.DueDay
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
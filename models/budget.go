@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Budget adalah batas pengeluaran bulanan untuk satu kategori
+type Budget struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Kategori  string             `bson:"kategori" json:"kategori"`
+	Bulan     string             `bson:"bulan" json:"bulan"` // format YYYY-MM
+	Limit     Money              `bson:"limit" json:"limit"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type CreateBudgetInput struct {
+	Kategori string `json:"kategori" binding:"required"`
+	Bulan    string `json:"bulan"` // default bulan ini
+	Limit    Money  `json:"limit" binding:"required,gt=0"`
+}
+
+type UpdateBudgetInput struct {
+	Limit Money `json:"limit" binding:"required,gt=0"`
+}
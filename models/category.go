@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Category struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Nama      string              `bson:"nama" json:"nama"`
+	Tipe      string              `bson:"tipe" json:"tipe"`                     // "pengeluaran" atau "pemasukan"
+	ParentID  *primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id"` // induk jika kategori ini subkategori
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+type CreateCategoryInput struct {
+	Nama     string `json:"nama" binding:"required"`
+	Tipe     string `json:"tipe" binding:"omitempty,oneof=pemasukan pengeluaran"` // default "pengeluaran"
+	ParentID string `json:"parent_id"`
+}
+
+type UpdateCategoryInput struct {
+	Nama     string  `json:"nama" binding:"omitempty"`
+	ParentID *string `json:"parent_id"` // "" menjadikan kategori sebagai kategori utama
+}
+
+// MergeCategoryInput memindahkan semua transaksi dari kategori From ke To.
+// Jika To belum ada, kategori pertama di From diganti namanya menjadi To.
+type MergeCategoryInput struct {
+	Tipe   string   `json:"tipe" binding:"omitempty,oneof=pemasukan pengeluaran"` // default "pengeluaran"
+	From   []string `json:"from" binding:"required,min=1,dive,required"`
+	To     string   `json:"to" binding:"required"`
+	DryRun bool     `json:"dry_run"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kategori untuk transaksi pembayaran utang maupun penerimaan piutang
+const DebtCategory = "Utang Piutang"
+
+// Debt mencatat utang (user meminjam dari counterparty) atau piutang
+// (counterparty meminjam dari user). Remaining berkurang setiap kali ada
+// pembayaran dan status menjadi "lunas" saat mencapai 0.
+type Debt struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Counterparty string             `bson:"counterparty" json:"counterparty"`
+	Direction    string             `bson:"direction" json:"direction"` // "utang" atau "piutang"
+	Principal    Money              `bson:"principal" json:"principal"`
+	Paid         Money              `bson:"paid" json:"paid"`
+	Remaining    Money              `bson:"remaining" json:"remaining"`
+	DueDate      *time.Time         `bson:"due_date,omitempty" json:"due_date"`
+	Catatan      string             `bson:"catatan" json:"catatan"`
+	Status       string             `bson:"status" json:"status"` // "aktif" atau "lunas"
+	PaidOffAt    *time.Time         `bson:"paid_off_at,omitempty" json:"paid_off_at"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// DebtPayment adalah satu kali pembayaran cicilan utang atau penerimaan piutang
+type DebtPayment struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	DebtID        primitive.ObjectID  `bson:"debt_id" json:"debt_id"`
+	Amount        Money               `bson:"amount" json:"amount"`
+	Catatan       string              `bson:"catatan" json:"catatan"`
+	Tanggal       time.Time           `bson:"tanggal" json:"tanggal"`
+	TransactionID *primitive.ObjectID `bson:"transaction_id,omitempty" json:"transaction_id,omitempty"`
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+}
+
+type CreateDebtInput struct {
+	Counterparty string `json:"counterparty" binding:"required"`
+	Direction    string `json:"direction" binding:"required,oneof=utang piutang"`
+	Principal    Money  `json:"principal" binding:"required,gt=0"`
+	DueDate      string `json:"due_date"` // format YYYY-MM-DD, opsional
+	Catatan      string `json:"catatan"`
+}
+
+type UpdateDebtInput struct {
+	Counterparty string  `json:"counterparty" binding:"omitempty"`
+	DueDate      *string `json:"due_date"` // "" untuk menghapus jatuh tempo
+	Catatan      *string `json:"catatan"`
+}
+
+// RecordTransaction mencatat pembayaran utang sebagai pengeluaran dan
+// penerimaan piutang sebagai pemasukan kategori Utang Piutang
+type DebtPaymentInput struct {
+	Amount            Money  `json:"amount" binding:"required,gt=0"`
+	Tanggal           string `json:"tanggal"` // format YYYY-MM-DD, default hari ini
+	Catatan           string `json:"catatan"`
+	RecordTransaction bool   `json:"record_transaction"`
+	AccountID         string `json:"account_id"`
+}
+
+// TransactionTipe returns the tipe of the transaction recorded for a payment
+func (d *Debt) TransactionTipe() string {
+	if d.Direction == "piutang" {
+		return "pemasukan"
+	}
+	return "pengeluaran"
+}
+
+// IsOverdue reports whether the debt is still unpaid after its due date
+func (d *Debt) IsOverdue(t time.Time) bool {
+	return d.Status == "aktif" && d.DueDate != nil && t.After(*d.DueDate)
+}
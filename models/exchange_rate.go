@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultCurrency adalah mata uang dasar user yang belum mengatur base_currency
+const DefaultCurrency = "IDR"
+
+// ExchangeRate adalah kurs yang diisi manual oleh user: 1 From = Rate To,
+// berlaku mulai Tanggal sampai ada kurs yang lebih baru
+type ExchangeRate struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	From      string             `bson:"from" json:"from"` // kode ISO 4217, misalnya "USD"
+	To        string             `bson:"to" json:"to"`     // biasanya mata uang dasar user
+	Rate      float64            `bson:"rate" json:"rate"`
+	Tanggal   time.Time          `bson:"tanggal" json:"tanggal"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type CreateExchangeRateInput struct {
+	From    string  `json:"from" binding:"required,iso4217"`
+	To      string  `json:"to" binding:"omitempty,iso4217"` // default mata uang dasar user
+	Rate    float64 `json:"rate" binding:"required,gt=0"`
+	Tanggal string  `json:"tanggal"` // format YYYY-MM-DD, default hari ini
+}
+
+type UpdateExchangeRateInput struct {
+	Rate    float64 `json:"rate" binding:"omitempty,gt=0"`
+	Tanggal string  `json:"tanggal"`
+}
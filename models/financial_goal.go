@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -10,28 +11,58 @@ type FinancialGoal struct {
 	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
 	Nama          string             `bson:"nama" json:"nama"`
-	TargetAmount  float64            `bson:"target_amount" json:"target_amount"`
-	CurrentAmount float64            `bson:"current_amount" json:"current_amount"`
+	TargetAmount  Money              `bson:"target_amount" json:"target_amount"`
+	CurrentAmount Money              `bson:"current_amount" json:"current_amount"`
+	Deadline      *time.Time         `bson:"deadline,omitempty" json:"deadline"`
+	Status        string             `bson:"status" json:"status"` // "active", "completed", "archived" atau "locked"
+	CompletedAt   *time.Time         `bson:"completed_at,omitempty" json:"completed_at"`
+	LockedUntil   *time.Time         `bson:"locked_until,omitempty" json:"locked_until"` // dana tidak dapat ditarik sebelum tanggal ini
+	Members       []Member           `bson:"members,omitempty" json:"members,omitempty"` // goal bersama
 	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
 type CreateGoalInput struct {
-	Nama         string  `json:"nama" binding:"required"`
-	TargetAmount float64 `json:"target_amount" binding:"required,gt=0"`
+	Nama         string `json:"nama" binding:"required"`
+	TargetAmount Money  `json:"target_amount" binding:"required,gt=0"`
+	Deadline     string `json:"deadline"` // format YYYY-MM-DD, opsional
 }
 
 type UpdateGoalInput struct {
 	Nama         string  `json:"nama" binding:"omitempty"`
-	TargetAmount float64 `json:"target_amount" binding:"omitempty,gt=0"`
+	TargetAmount Money   `json:"target_amount" binding:"omitempty,gt=0"`
+	Deadline     *string `json:"deadline"` // "" untuk menghapus deadline
 }
 
+// RecordTransaction mencatat setoran sebagai pengeluaran kategori Goals
+// sehingga saldo di summary ikut berkurang
 type AddProgressInput struct {
-	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Amount            Money  `json:"amount" binding:"required,gt=0"`
+	RecordTransaction bool   `json:"record_transaction"`
+	AccountID         string `json:"account_id"`
+	Catatan           string `json:"catatan"`
 }
 
+// RecordTransaction mencatat penarikan sebagai pemasukan kategori Goals
 type WithdrawProgressInput struct {
-	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Amount            Money  `json:"amount" binding:"required,gt=0"`
+	RecordTransaction bool   `json:"record_transaction"`
+	AccountID         string `json:"account_id"`
+	Catatan           string `json:"catatan"`
+}
+
+type LockGoalInput struct {
+	LockedUntil string `json:"locked_until" binding:"required"` // format YYYY-MM-DD
+}
+
+// RoleOf returns "owner", "editor", "viewer" or "" for userID
+func (g *FinancialGoal) RoleOf(userID primitive.ObjectID) string {
+	return roleOf(g.UserID, g.Members, userID)
+}
+
+// IsLocked reports whether withdrawals are still refused at t
+func (g *FinancialGoal) IsLocked(t time.Time) bool {
+	return g.Status == "locked" && g.LockedUntil != nil && t.Before(*g.LockedUntil)
 }
 
 // Helper function to calculate progress percentage
@@ -39,9 +70,85 @@ func (g *FinancialGoal) GetProgressPercentage() float64 {
 	if g.TargetAmount == 0 {
 		return 0
 	}
-	percentage := (g.CurrentAmount / g.TargetAmount) * 100
+	percentage := g.CurrentAmount.Ratio(g.TargetAmount) * 100
 	if percentage > 100 {
 		return 100
 	}
 	return percentage
 }
+
+// averageDaysPerMonth dipakai untuk mengubah selisih hari menjadi bulan
+const averageDaysPerMonth = 30.4375
+
+// GoalInsight berisi perhitungan menuju deadline goal
+type GoalInsight struct {
+	Remaining       Money      `json:"remaining"`
+	MonthsRemaining float64    `json:"months_remaining"` // bulan tersisa sampai deadline
+	MonthlyNeeded   Money      `json:"monthly_needed"`   // setoran per bulan agar tercapai tepat waktu
+	MonthlyRate     Money      `json:"monthly_rate"`     // rata-rata tabungan bersih per bulan dalam beberapa bulan terakhir
+	ProjectedDate   *time.Time `json:"projected_date"`   // perkiraan tanggal tercapai dengan laju saat ini
+	Status          string     `json:"status"`           // "completed", "on_track", "behind" atau "no_deadline"
+}
+
+// GoalRateWindowMonths adalah rentang riwayat kontribusi yang dipakai untuk
+// menghitung laju tabungan goal
+const GoalRateWindowMonths = 3
+
+// GoalRateWindowStart mengembalikan awal rentang riwayat kontribusi untuk laju tabungan
+func GoalRateWindowStart(now time.Time) time.Time {
+	return now.AddDate(0, -GoalRateWindowMonths, 0)
+}
+
+// Insight menghitung kebutuhan tabungan bulanan dan perkiraan tanggal
+// tercapai. recentNet adalah total setoran dikurangi penarikan sejak
+// GoalRateWindowStart(now), diambil dari riwayat goal_contributions.
+func (g *FinancialGoal) Insight(now time.Time, recentNet Money) GoalInsight {
+	insight := GoalInsight{Remaining: g.TargetAmount - g.CurrentAmount}
+	if insight.Remaining <= 0 {
+		insight.Remaining = 0
+		insight.Status = "completed"
+		return insight
+	}
+
+	// Goal yang baru dibuat di tengah rentang dihitung sejak tanggal dibuat
+	since := GoalRateWindowStart(now)
+	if g.CreatedAt.After(since) {
+		since = g.CreatedAt
+	}
+	months := now.Sub(since).Hours() / 24 / averageDaysPerMonth
+	if months < 1 {
+		months = 1
+	}
+	if recentNet > 0 {
+		insight.MonthlyRate = recentNet.MulRatio(1 / months)
+	}
+
+	if insight.MonthlyRate > 0 {
+		days := insight.Remaining.Ratio(insight.MonthlyRate) * averageDaysPerMonth
+		projected := now.AddDate(0, 0, int(math.Ceil(days)))
+		insight.ProjectedDate = &projected
+	}
+
+	if g.Deadline == nil {
+		insight.Status = "no_deadline"
+		return insight
+	}
+
+	insight.MonthsRemaining = g.Deadline.Sub(now).Hours() / 24 / averageDaysPerMonth
+	if insight.MonthsRemaining < 0 {
+		insight.MonthsRemaining = 0
+	}
+	if insight.MonthsRemaining < 1 {
+		// Sisa target harus terkumpul bulan ini
+		insight.MonthlyNeeded = insight.Remaining
+	} else {
+		insight.MonthlyNeeded = insight.Remaining.MulRatio(1 / insight.MonthsRemaining)
+	}
+
+	if insight.ProjectedDate != nil && !insight.ProjectedDate.After(*g.Deadline) {
+		insight.Status = "on_track"
+	} else {
+		insight.Status = "behind"
+	}
+	return insight
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GoalContribution mencatat setiap setoran atau penarikan dana goal
+type GoalContribution struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	GoalID        primitive.ObjectID  `bson:"goal_id" json:"goal_id"`
+	Amount        Money               `bson:"amount" json:"amount"`
+	Direction     string              `bson:"direction" json:"direction"` // "setor" atau "tarik"
+	Catatan       string              `bson:"catatan" json:"catatan"`
+	TransactionID *primitive.ObjectID `bson:"transaction_id,omitempty" json:"transaction_id,omitempty"` // transaksi Goals yang tercatat, jika ada
+	RuleID        *primitive.ObjectID `bson:"rule_id,omitempty" json:"rule_id,omitempty"`               // aturan otomatis yang membuat setoran ini
+	SourceID      *primitive.ObjectID `bson:"source_id,omitempty" json:"source_id,omitempty"`           // transaksi yang memicu aturan otomatis
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// GoalMonthlySaving adalah total setoran dan penarikan goal dalam satu bulan
+type GoalMonthlySaving struct {
+	Bulan      string `bson:"_id" json:"bulan"` // format YYYY-MM
+	Setor      Money  `bson:"setor" json:"setor"`
+	Tarik      Money  `bson:"tarik" json:"tarik"`
+	Net        Money  `bson:"-" json:"net"`
+	Cumulative Money  `bson:"-" json:"cumulative"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GoalRule menabung ke goal secara otomatis setiap ada transaksi baru:
+//   - "persentase": Persentase% dari setiap pemasukan (opsional hanya kategori tertentu)
+//   - "pembulatan": membulatkan setiap pengeluaran ke atas ke kelipatan Kelipatan
+//     dan menabung selisihnya
+type GoalRule struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID            primitive.ObjectID `bson:"user_id" json:"user_id"`
+	GoalID            primitive.ObjectID `bson:"goal_id" json:"goal_id"`
+	Tipe              string             `bson:"tipe" json:"tipe"` // "persentase" atau "pembulatan"
+	Persentase        float64            `bson:"persentase,omitempty" json:"persentase,omitempty"`
+	Kelipatan         Money              `bson:"kelipatan,omitempty" json:"kelipatan,omitempty"`
+	Kategori          string             `bson:"kategori,omitempty" json:"kategori,omitempty"` // kosong berarti semua kategori
+	RecordTransaction bool               `bson:"record_transaction" json:"record_transaction"`
+	IsActive          bool               `bson:"is_active" json:"is_active"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type CreateGoalRuleInput struct {
+	Tipe              string  `json:"tipe" binding:"required,oneof=persentase pembulatan"`
+	Persentase        float64 `json:"persentase" binding:"omitempty,gt=0,lte=100"`
+	Kelipatan         Money   `json:"kelipatan" binding:"omitempty,gt=0"`
+	Kategori          string  `json:"kategori"`
+	RecordTransaction bool    `json:"record_transaction"`
+}
+
+type UpdateGoalRuleInput struct {
+	Persentase        float64 `json:"persentase" binding:"omitempty,gt=0,lte=100"`
+	Kelipatan         Money   `json:"kelipatan" binding:"omitempty,gt=0"`
+	Kategori          *string `json:"kategori"` // "" untuk semua kategori
+	RecordTransaction *bool   `json:"record_transaction"`
+	IsActive          *bool   `json:"is_active"`
+}
+
+// TransactionTipe returns the transaction tipe the rule reacts to
+func (r *GoalRule) TransactionTipe() string {
+	if r.Tipe == "pembulatan" {
+		return "pengeluaran"
+	}
+	return "pemasukan"
+}
+
+// AmountFor returns how much the rule saves for a transaction, or 0 if the
+// rule does not apply to it
+func (r *GoalRule) AmountFor(t *Transaction) Money {
+	if !r.IsActive || t.Tipe != r.TransactionTipe() || t.GoalID != nil {
+		return 0
+	}
+
+	// Pada transaksi split hanya bagian dengan kategori aturan yang dihitung
+	base := t.Nominal
+	if r.Kategori != "" {
+		base = t.AmountIn(r.Kategori)
+	}
+	if base <= 0 {
+		return 0
+	}
+
+	switch r.Tipe {
+	case "persentase":
+		return base.MulRatio(r.Persentase / 100)
+	case "pembulatan":
+		if r.Kelipatan <= 0 {
+			return 0
+		}
+		return (base+r.Kelipatan-1)/r.Kelipatan*r.Kelipatan - base
+	}
+	return 0
+}
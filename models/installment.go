@@ -0,0 +1,136 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Installment adalah cicilan (HP, motor) atau paket PayLater dengan jumlah
+// pembayaran bulanan yang tetap. Jadwal pembayaran dibuat saat cicilan
+// dibuat dan setiap angsuran ditandai lunas saat transaksi yang cocok dicatat.
+type Installment struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Nama          string              `bson:"nama" json:"nama"`
+	Tipe          string              `bson:"tipe" json:"tipe"` // "cicilan" atau "paylater"
+	Provider      string              `bson:"provider" json:"provider"`
+	Kategori      string              `bson:"kategori" json:"kategori"` // kategori pengeluaran untuk mencocokkan transaksi
+	AccountID     *primitive.ObjectID `bson:"account_id,omitempty" json:"account_id"`
+	MonthlyAmount Money               `bson:"monthly_amount" json:"monthly_amount"`
+	Tenor         int                 `bson:"tenor" json:"tenor"` // jumlah bulan
+	Schedule      []InstallmentDue    `bson:"schedule" json:"schedule"`
+	Status        string              `bson:"status" json:"status"` // "aktif" atau "lunas"
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// InstallmentDue adalah satu angsuran pada jadwal cicilan
+type InstallmentDue struct {
+	Ke            int                 `bson:"ke" json:"ke"`
+	DueDate       time.Time           `bson:"due_date" json:"due_date"`
+	Amount        Money               `bson:"amount" json:"amount"`
+	Status        string              `bson:"status" json:"status"` // "belum" atau "lunas"
+	PaidAt        *time.Time          `bson:"paid_at,omitempty" json:"paid_at,omitempty"`
+	TransactionID *primitive.ObjectID `bson:"transaction_id,omitempty" json:"transaction_id,omitempty"`
+}
+
+// InstallmentProgress merangkum sisa tenor dan sisa tagihan cicilan
+type InstallmentProgress struct {
+	PaidCount      int             `json:"paid_count"`
+	RemainingTenor int             `json:"remaining_tenor"`
+	TotalAmount    Money           `json:"total_amount"`
+	Outstanding    Money           `json:"outstanding"`
+	NextDue        *InstallmentDue `json:"next_due"`
+}
+
+// UpcomingInstallmentDue adalah angsuran yang belum dibayar beserta cicilannya
+type UpcomingInstallmentDue struct {
+	InstallmentID primitive.ObjectID `json:"installment_id"`
+	Nama          string             `json:"nama"`
+	Provider      string             `json:"provider"`
+	InstallmentDue
+	IsOverdue bool `json:"is_overdue"`
+}
+
+type CreateInstallmentInput struct {
+	Nama          string `json:"nama" binding:"required"`
+	Tipe          string `json:"tipe" binding:"required,oneof=cicilan paylater"`
+	Provider      string `json:"provider"`
+	Kategori      string `json:"kategori" binding:"required"`
+	AccountID     string `json:"account_id"`
+	MonthlyAmount Money  `json:"monthly_amount" binding:"required,gt=0"`
+	Tenor         int    `json:"tenor" binding:"required,gt=0,lte=120"`
+	FirstDueDate  string `json:"first_due_date" binding:"required"` // format YYYY-MM-DD
+}
+
+type UpdateInstallmentInput struct {
+	Nama      string  `json:"nama" binding:"omitempty"`
+	Provider  *string `json:"provider"`
+	Kategori  string  `json:"kategori" binding:"omitempty"`
+	AccountID *string `json:"account_id"` // "" untuk melepas akun
+}
+
+// BuildInstallmentSchedule membuat jadwal angsuran bulanan mulai firstDue.
+// Tanggal yang tidak ada di bulan tersebut (misalnya 31) dijatuhkan ke hari
+// terakhir bulan itu.
+func BuildInstallmentSchedule(firstDue time.Time, tenor int, amount Money) []InstallmentDue {
+	schedule := make([]InstallmentDue, 0, tenor)
+	for i := 0; i < tenor; i++ {
+		schedule = append(schedule, InstallmentDue{
+			Ke:      i + 1,
+			DueDate: addMonthsClamped(firstDue, i),
+			Amount:  amount,
+			Status:  "belum",
+		})
+	}
+	return schedule
+}
+
+// addMonthsClamped menambah n bulan tanpa melompat ke bulan berikutnya
+func addMonthsClamped(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, t.Location())
+}
+
+// NextUnpaid returns the earliest unpaid due, or nil if everything is paid
+func (in *Installment) NextUnpaid() *InstallmentDue {
+	for i := range in.Schedule {
+		if in.Schedule[i].Status != "lunas" {
+			return &in.Schedule[i]
+		}
+	}
+	return nil
+}
+
+// Progress returns paid count, remaining tenor and outstanding balance
+func (in *Installment) Progress() InstallmentProgress {
+	progress := InstallmentProgress{NextDue: in.NextUnpaid()}
+	for _, due := range in.Schedule {
+		progress.TotalAmount += due.Amount
+		if due.Status == "lunas" {
+			progress.PaidCount++
+		} else {
+			progress.RemainingTenor++
+			progress.Outstanding += due.Amount
+		}
+	}
+	return progress
+}
+
+// Matches reports whether an expense transaction pays the next unpaid due
+// of this installment: same account, same kategori and the same amount.
+// Cicilan tanpa akun dan transaksi dari jadwal berulang tidak pernah
+// dicocokkan otomatis, hanya lewat installment_id.
+func (in *Installment) Matches(t *Transaction) bool {
+	if in.AccountID == nil || t.AccountID == nil || *in.AccountID != *t.AccountID || t.RecurringID != nil {
+		return false
+	}
+	next := in.NextUnpaid()
+	return next != nil && t.Tipe == "pengeluaran" && t.Kategori == in.Kategori && t.Nominal == next.Amount
+}
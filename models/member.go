@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Member adalah user lain yang diberi akses ke goal atau akun bersama.
+// Pemilik (user_id pada goal/akun) selalu berperan "owner" dan tidak
+// disimpan di daftar members.
+type Member struct {
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Username string             `bson:"username" json:"username"`
+	Role     string             `bson:"role" json:"role"` // "editor" atau "viewer"
+	JoinedAt time.Time          `bson:"joined_at" json:"joined_at"`
+}
+
+// Invitation adalah undangan untuk bergabung ke goal atau akun bersama
+type Invitation struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ResourceType    string             `bson:"resource_type" json:"resource_type"` // "goal" atau "account"
+	ResourceID      primitive.ObjectID `bson:"resource_id" json:"resource_id"`
+	ResourceNama    string             `bson:"resource_nama" json:"resource_nama"`
+	InviterID       primitive.ObjectID `bson:"inviter_id" json:"inviter_id"`
+	InviterUsername string             `bson:"inviter_username" json:"inviter_username"`
+	InviteeID       primitive.ObjectID `bson:"invitee_id" json:"invitee_id"`
+	InviteeUsername string             `bson:"invitee_username" json:"invitee_username"`
+	Role            string             `bson:"role" json:"role"`
+	Status          string             `bson:"status" json:"status"` // "pending", "accepted" atau "declined"
+	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
+	RespondedAt     *time.Time         `bson:"responded_at,omitempty" json:"responded_at,omitempty"`
+}
+
+type InviteMemberInput struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"required,oneof=editor viewer"`
+}
+
+type UpdateMemberInput struct {
+	Role string `json:"role" binding:"required,oneof=editor viewer"`
+}
+
+// roleOf returns the role of userID on a resource owned by ownerID, or ""
+// if the user has no access
+func roleOf(ownerID primitive.ObjectID, members []Member, userID primitive.ObjectID) string {
+	if ownerID == userID {
+		return "owner"
+	}
+	for _, m := range members {
+		if m.UserID == userID {
+			return m.Role
+		}
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// MoneyScale adalah jumlah satuan terkecil (sen) dalam satu rupiah
+const MoneyScale = 100
+
+// Money adalah nominal uang dalam satuan terkecil (1/100) yang disimpan di
+// MongoDB sebagai int64 sehingga penjumlahan dan $sum selalu tepat. Di JSON
+// nilainya tetap ditulis sebagai angka biasa, misalnya 50000 atau 12.5.
+type Money int64
+
+// NewMoney converts an amount in whole units (e.g. rupiah) to Money,
+// rounding to the nearest minor unit
+func NewMoney(amount float64) Money {
+	return Money(math.Round(amount * MoneyScale))
+}
+
+// Float returns the amount in whole units, for ratios and display only
+func (m Money) Float() float64 {
+	return float64(m) / MoneyScale
+}
+
+// MulRatio returns m multiplied by ratio, rounded to the nearest minor unit
+func (m Money) MulRatio(ratio float64) Money {
+	return Money(math.Round(float64(m) * ratio))
+}
+
+// Ratio returns m / other, or 0 if other is zero
+func (m Money) Ratio(other Money) float64 {
+	if other == 0 {
+		return 0
+	}
+	return float64(m) / float64(other)
+}
+
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	whole, frac := v/MoneyScale, v%MoneyScale
+	if frac == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	return strings.TrimRight(fmt.Sprintf("%s%d.%02d", sign, whole, frac), "0")
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		return nil
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return fmt.Errorf("nominal tidak valid: %s", s)
+	}
+	*m = parsed
+	return nil
+}
+
+// ParseMoney parses a decimal string such as "50000" or "12.5" exactly.
+// Digits beyond the minor unit are rounded.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if strings.ContainsAny(s, "eE") || (hasFrac && len(frac) > 2) {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, err
+		}
+		return NewMoney(f), nil
+	}
+
+	negative := strings.HasPrefix(whole, "-")
+	w, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	var f int64
+	if hasFrac && frac != "" {
+		f, err = strconv.ParseInt((frac + "00")[:2], 10, 64)
+		if err != nil || f < 0 {
+			return 0, fmt.Errorf("invalid fraction %q", frac)
+		}
+	}
+	if negative {
+		f = -f
+	}
+	return Money(w*MoneyScale + f), nil
+}
+
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bsontype.Int64, bsoncore.AppendInt64(nil, int64(m)), nil
+}
+
+// UnmarshalBSONValue membaca int64/int32 sebagai satuan terkecil. Nilai
+// double dan Decimal128 adalah data lama (sebelum migrasi) dalam rupiah utuh.
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bsoncore.Value{Type: t, Data: data}
+	switch t {
+	case bsontype.Int64:
+		*m = Money(value.Int64())
+	case bsontype.Int32:
+		*m = Money(value.Int32())
+	case bsontype.Double:
+		*m = NewMoney(value.Double())
+	case bsontype.Decimal128:
+		parsed, err := ParseMoney(value.Decimal128().String())
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case bsontype.Null, bsontype.Undefined:
+		*m = 0
+	default:
+		return fmt.Errorf("cannot decode %v into Money", t)
+	}
+	return nil
+}
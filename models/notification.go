@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Notification struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Tipe      string              `bson:"tipe" json:"tipe"` // "budget_alert"
+	Judul     string              `bson:"judul" json:"judul"`
+	Pesan     string              `bson:"pesan" json:"pesan"`
+	BudgetID  *primitive.ObjectID `bson:"budget_id,omitempty" json:"budget_id,omitempty"`
+	Bulan     string              `bson:"bulan,omitempty" json:"bulan,omitempty"`         // bulan budget, format YYYY-MM
+	Limit     Money               `bson:"limit,omitempty" json:"limit,omitempty"`         // limit budget saat threshold terlewati
+	Threshold int                 `bson:"threshold,omitempty" json:"threshold,omitempty"` // persentase budget: 80 atau 100
+	IsRead    bool                `bson:"is_read" json:"is_read"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecurringTransaction adalah jadwal transaksi berulang seperti gaji, kos,
+// internet atau langganan. Scheduler membuat models.Transaction untuk setiap
+// tanggal jatuh tempo (NextRun) yang sudah lewat.
+type RecurringTransaction struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID         primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	AccountID      *primitive.ObjectID `bson:"account_id,omitempty" json:"account_id"`
+	Tipe           string              `bson:"tipe" json:"tipe"` // "pemasukan" atau "pengeluaran"
+	Nominal        Money               `bson:"nominal" json:"nominal"`
+	Kategori       string              `bson:"kategori" json:"kategori"`
+	Catatan        string              `bson:"catatan" json:"catatan"`
+	Frekuensi      string              `bson:"frekuensi" json:"frekuensi"`           // "harian", "mingguan", "bulanan" atau "tahunan"
+	Hari           int                 `bson:"hari,omitempty" json:"hari,omitempty"` // tanggal 1-31 untuk frekuensi bulanan
+	TanggalMulai   time.Time           `bson:"tanggal_mulai" json:"tanggal_mulai"`
+	TanggalSelesai *time.Time          `bson:"tanggal_selesai,omitempty" json:"tanggal_selesai"`
+	NextRun        time.Time           `bson:"next_run" json:"next_run"`
+	IsPaused       bool                `bson:"is_paused" json:"is_paused"`
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+type CreateRecurringTransactionInput struct {
+	Tipe           string `json:"tipe" binding:"required,oneof=pemasukan pengeluaran"`
+	Nominal        Money  `json:"nominal" binding:"required,gt=0"`
+	Kategori       string `json:"kategori" binding:"required"`
+	Catatan        string `json:"catatan"`
+	AccountID      string `json:"account_id"`
+	Frekuensi      string `json:"frekuensi" binding:"required,oneof=harian mingguan bulanan tahunan"`
+	Hari           int    `json:"hari" binding:"omitempty,min=1,max=31"`
+	TanggalMulai   string `json:"tanggal_mulai" binding:"required"`
+	TanggalSelesai string `json:"tanggal_selesai"`
+}
+
+type UpdateRecurringTransactionInput struct {
+	Nominal        Money  `json:"nominal" binding:"omitempty,gt=0"`
+	Kategori       string `json:"kategori"`
+	Catatan        string `json:"catatan"`
+	AccountID      string `json:"account_id"`
+	TanggalSelesai string `json:"tanggal_selesai"`
+}
+
+// Occurrence returns the n-th scheduled date counted from TanggalMulai.
+// Monthly and yearly dates that do not exist (e.g. the 31st in February)
+// fall on the last day of that month.
+func (r *RecurringTransaction) Occurrence(n int) time.Time {
+	start := r.TanggalMulai
+	switch r.Frekuensi {
+	case "harian":
+		return start.AddDate(0, 0, n)
+	case "mingguan":
+		return start.AddDate(0, 0, 7*n)
+	case "bulanan":
+		day := r.Hari
+		if day == 0 {
+			day = start.Day()
+		}
+		return clampedDate(start.Year(), start.Month()+time.Month(n), day, start.Location())
+	case "tahunan":
+		return clampedDate(start.Year()+n, start.Month(), start.Day(), start.Location())
+	}
+	return start
+}
+
+// NextOccurrence returns the first scheduled date strictly after t
+func (r *RecurringTransaction) NextOccurrence(t time.Time) time.Time {
+	for n := 0; ; n++ {
+		occurrence := r.Occurrence(n)
+		if occurrence.After(t) && !occurrence.Before(r.TanggalMulai) {
+			return occurrence
+		}
+	}
+}
+
+// IsEnded reports whether the schedule has no occurrence left at t
+func (r *RecurringTransaction) IsEnded(t time.Time) bool {
+	return r.TanggalSelesai != nil && t.After(*r.TanggalSelesai)
+}
+
+// clampedDate builds a date, moving days past the end of the month back to its last day
+func clampedDate(year int, month time.Month, day int, loc *time.Location) time.Time {
+	firstOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(firstOfMonth.Year(), firstOfMonth.Month(), day, 0, 0, 0, 0, loc)
+}
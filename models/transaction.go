@@ -1,12 +1,16 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Kategori pengeluaran yang diizinkan
+// Kategori untuk transaksi yang terhubung dengan goal (setoran maupun penarikan)
+const GoalCategory = "Goals"
+
+// Kategori pengeluaran bawaan, disalin ke koleksi categories milik setiap user
 var AllowedCategories = []string{
 	"Makanan & Minuman",
 	"Transportasi",
@@ -19,40 +23,145 @@ var AllowedCategories = []string{
 	"Lainnya",
 }
 
+// Kategori (sumber) pemasukan bawaan, disalin ke koleksi categories milik setiap user
+var DefaultIncomeCategories = []string{
+	"Gaji",
+	"Bonus",
+	"Freelance",
+	"Investasi",
+	"Hadiah",
+	"Lainnya",
+}
+
 type Transaction struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Tipe      string             `bson:"tipe" json:"tipe"`           // "pemasukan" atau "pengeluaran"
-	Nominal   float64            `bson:"nominal" json:"nominal"`
-	Kategori  string             `bson:"kategori" json:"kategori"`   // hanya untuk pengeluaran
-	Catatan   string             `bson:"catatan" json:"catatan"`
-	Tanggal   time.Time          `bson:"tanggal" json:"tanggal"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	AccountID     *primitive.ObjectID `bson:"account_id,omitempty" json:"account_id"`
+	ToAccountID   *primitive.ObjectID `bson:"to_account_id,omitempty" json:"to_account_id,omitempty"` // hanya untuk transfer
+	Tipe          string              `bson:"tipe" json:"tipe"`                                       // "pemasukan", "pengeluaran" atau "transfer"
+	Nominal       Money               `bson:"nominal" json:"nominal"`
+	Currency      string              `bson:"currency,omitempty" json:"currency,omitempty"` // kosong berarti mata uang dasar user
+	Kategori      string              `bson:"kategori" json:"kategori"`                     // kategori pengeluaran atau sumber pemasukan
+	Splits        []TransactionSplit  `bson:"splits,omitempty" json:"splits,omitempty"`     // rincian per kategori, jumlahnya sama dengan Nominal
+	Catatan       string              `bson:"catatan" json:"catatan"`
+	Tags          []string            `bson:"tags,omitempty" json:"tags,omitempty"` // label lintas kategori, misalnya "liburan-bali" atau "reimburse"
+	Receipts      []Receipt           `bson:"receipts,omitempty" json:"receipts,omitempty"`
+	Tanggal       time.Time           `bson:"tanggal" json:"tanggal"`
+	RecurringID   *primitive.ObjectID `bson:"recurring_id,omitempty" json:"recurring_id,omitempty"`     // jadwal berulang yang membuat transaksi ini
+	GoalID        *primitive.ObjectID `bson:"goal_id,omitempty" json:"goal_id,omitempty"`               // goal yang menerima/melepas dana dari transaksi ini
+	DebtID        *primitive.ObjectID `bson:"debt_id,omitempty" json:"debt_id,omitempty"`               // utang/piutang yang dibayar dengan transaksi ini
+	InstallmentID *primitive.ObjectID `bson:"installment_id,omitempty" json:"installment_id,omitempty"` // cicilan yang angsurannya dibayar dengan transaksi ini
+	BillID        *primitive.ObjectID `bson:"bill_id,omitempty" json:"bill_id,omitempty"`               // tagihan yang dibayar dengan transaksi ini
+	BillPeriode   string              `bson:"bill_periode,omitempty" json:"bill_periode,omitempty"`     // periode tagihan (YYYY-MM) yang dibayar
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
 }
 
 type CreateTransactionInput struct {
-	Tipe     string  `json:"tipe" binding:"required,oneof=pemasukan pengeluaran"`
-	Nominal  float64 `json:"nominal" binding:"required,gt=0"`
-	Kategori string  `json:"kategori"`
-	Catatan  string  `json:"catatan"`
-	Tanggal  string  `json:"tanggal" binding:"required"`
+	Tipe          string                  `json:"tipe" binding:"required,oneof=pemasukan pengeluaran"`
+	Nominal       Money                   `json:"nominal" binding:"required,gt=0"`
+	Currency      string                  `json:"currency" binding:"omitempty,iso4217"` // default mata uang akun atau mata uang dasar
+	Kategori      string                  `json:"kategori"`
+	Catatan       string                  `json:"catatan"`
+	Tanggal       string                  `json:"tanggal" binding:"required"`
+	AccountID     string                  `json:"account_id"`
+	InstallmentID string                  `json:"installment_id"` // opsional, cicilan yang angsurannya dibayar
+	Splits        []TransactionSplitInput `json:"splits" binding:"omitempty,dive"`
+	Tags          []string                `json:"tags" binding:"omitempty,max=10,dive,max=30"`
 }
 
 type UpdateTransactionInput struct {
-	Tipe     string  `json:"tipe" binding:"omitempty,oneof=pemasukan pengeluaran"`
-	Nominal  float64 `json:"nominal" binding:"omitempty,gt=0"`
-	Kategori string  `json:"kategori"`
-	Catatan  string  `json:"catatan"`
-	Tanggal  string  `json:"tanggal"`
-}
-
-// Helper function to check if category is valid
-func IsValidCategory(category string) bool {
-	for _, c := range AllowedCategories {
-		if c == category {
-			return true
+	Tipe      string                  `json:"tipe" binding:"omitempty,oneof=pemasukan pengeluaran"`
+	Nominal   Money                   `json:"nominal" binding:"omitempty,gt=0"`
+	Currency  string                  `json:"currency" binding:"omitempty,iso4217"`
+	Kategori  string                  `json:"kategori"`
+	Catatan   string                  `json:"catatan"`
+	Tanggal   string                  `json:"tanggal"`
+	AccountID string                  `json:"account_id"`
+	Splits    []TransactionSplitInput `json:"splits" binding:"omitempty,dive"`             // [] untuk menghapus split
+	Tags      []string                `json:"tags" binding:"omitempty,max=10,dive,max=30"` // [] untuk menghapus semua tag
+}
+
+// TransactionSplit adalah satu baris rincian transaksi split, misalnya satu
+// struk supermarket yang berisi belanja dapur, perlengkapan rumah dan jajan
+type TransactionSplit struct {
+	Kategori string `bson:"kategori" json:"kategori"`
+	Nominal  Money  `bson:"nominal" json:"nominal"`
+	Catatan  string `bson:"catatan,omitempty" json:"catatan,omitempty"`
+}
+
+// Receipt adalah foto struk yang dilampirkan pada transaksi
+type Receipt struct {
+	FileID     string    `bson:"file_id" json:"file_id"` // ID file di storage, dipakai untuk menghapus
+	URL        string    `bson:"url" json:"url"`
+	Nama       string    `bson:"nama" json:"nama"` // nama file asli
+	MimeType   string    `bson:"mime_type" json:"mime_type"`
+	Size       int64     `bson:"size" json:"size"`
+	UploadedAt time.Time `bson:"uploaded_at" json:"uploaded_at"`
+}
+
+type TransactionSplitInput struct {
+	Kategori string `json:"kategori" binding:"required"`
+	Nominal  Money  `json:"nominal" binding:"required,gt=0"`
+	Catatan  string `json:"catatan"`
+}
+
+// Transfer antar akun tidak dihitung sebagai pemasukan maupun pengeluaran
+type CreateTransferInput struct {
+	FromAccountID string `json:"from_account_id" binding:"required"`
+	ToAccountID   string `json:"to_account_id" binding:"required"`
+	Nominal       Money  `json:"nominal" binding:"required,gt=0"`
+	Catatan       string `json:"catatan"`
+	Tanggal       string `json:"tanggal" binding:"required"`
+}
+
+// NormalizeTags trims and lowercases tags and drops empty and duplicate ones,
+// so "Kantor" and "kantor " are the same tag
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// Categories returns the categories the transaction is attributed to: every
+// split kategori for a split transaction, otherwise its own kategori
+func (t *Transaction) Categories() []string {
+	if len(t.Splits) == 0 {
+		return []string{t.Kategori}
+	}
+
+	categories := []string{}
+	seen := map[string]bool{}
+	for _, s := range t.Splits {
+		if !seen[s.Kategori] {
+			seen[s.Kategori] = true
+			categories = append(categories, s.Kategori)
+		}
+	}
+	return categories
+}
+
+// AmountIn returns the part of the transaction attributed to kategori
+func (t *Transaction) AmountIn(kategori string) Money {
+	if len(t.Splits) == 0 {
+		if t.Kategori == kategori {
+			return t.Nominal
+		}
+		return 0
+	}
+
+	var total Money
+	for _, s := range t.Splits {
+		if s.Kategori == kategori {
+			total += s.Nominal
 		}
 	}
-	return false
+	return total
 }
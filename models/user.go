@@ -7,13 +7,17 @@ import (
 )
 
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Username  string             `bson:"username" json:"username"`
-	Password  string             `bson:"password" json:"-"`
-	Nama      string             `bson:"nama" json:"nama"`
-	Foto      string             `bson:"foto" json:"foto"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID                     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Username               string             `bson:"username" json:"username"`
+	Password               string             `bson:"password" json:"-"`
+	Nama                   string             `bson:"nama" json:"nama"`
+	Foto                   string             `bson:"foto" json:"foto"`
+	CategoriesSeeded       bool               `bson:"categories_seeded" json:"-"`        // kategori pengeluaran bawaan sudah disalin
+	IncomeCategoriesSeeded bool               `bson:"income_categories_seeded" json:"-"` // kategori pemasukan bawaan sudah disalin
+	CalendarToken          string             `bson:"calendar_token,omitempty" json:"-"` // token rahasia feed kalender tagihan (ICS)
+	BaseCurrency           string             `bson:"base_currency,omitempty" json:"base_currency"`
+	CreatedAt              time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt              time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
 type RegisterInput struct {
@@ -28,11 +32,20 @@ type LoginInput struct {
 }
 
 type UpdateProfileInput struct {
-	Nama string `json:"nama" binding:"omitempty,min=2"`
-	Foto string `json:"foto" binding:"omitempty,url"`
+	Nama         string `json:"nama" binding:"omitempty,min=2"`
+	Foto         string `json:"foto" binding:"omitempty,url"`
+	BaseCurrency string `json:"base_currency" binding:"omitempty,iso4217"`
 }
 
 type ChangePasswordInput struct {
 	OldPassword string `json:"old_password" binding:"required"`
 	NewPassword string `json:"new_password" binding:"required,min=6"`
 }
+
+// Currency returns the user's base currency, IDR if it was never set
+func (u *User) Currency() string {
+	if u.BaseCurrency == "" {
+		return DefaultCurrency
+	}
+	return u.BaseCurrency
+}
@@ -3,11 +3,17 @@ package routes
 import (
 	"DompetKu/controllers"
 	"DompetKu/middleware"
+	"DompetKu/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRoutes(router *gin.Engine) {
+	// File upload disajikan langsung oleh server saat memakai storage lokal
+	if local, ok := utils.GetStorage().(*utils.LocalStorage); ok {
+		router.Static(utils.LocalStorageRoute, local.Dir)
+	}
+
 	// Public routes
 	api := router.Group("/api")
 	{
@@ -18,8 +24,11 @@ func SetupRoutes(router *gin.Engine) {
 			auth.POST("/login", controllers.Login)
 		}
 
-		// Get categories (public)
-		api.GET("/categories", controllers.GetCategories)
+		// Get categories (public, personalised when a token is sent)
+		api.GET("/categories", middleware.OptionalAuthMiddleware(), controllers.GetCategories)
+
+		// Bill calendar feed (public, secret token in the URL)
+		api.GET("/bills/calendar/:token", controllers.GetBillCalendar)
 
 		// Protected routes
 		protected := api.Group("")
@@ -41,6 +50,38 @@ func SetupRoutes(router *gin.Engine) {
 				transactions.GET("/:id", controllers.GetTransactionByID)
 				transactions.PUT("/:id", controllers.UpdateTransaction)
 				transactions.DELETE("/:id", controllers.DeleteTransaction)
+				transactions.POST("/:id/receipts", controllers.UploadTransactionReceipts)
+				transactions.DELETE("/:id/receipts/*fileId", controllers.DeleteTransactionReceipt)
+			}
+
+			// Category routes
+			categories := protected.Group("/categories")
+			{
+				categories.POST("", controllers.CreateCategory)
+				categories.POST("/merge", controllers.MergeCategories)
+				categories.PUT("/:id", controllers.UpdateCategory)
+				categories.DELETE("/:id", controllers.DeleteCategory)
+			}
+
+			// Account (dompet) routes
+			accounts := protected.Group("/accounts")
+			{
+				accounts.POST("", controllers.CreateAccount)
+				accounts.GET("", controllers.GetAccounts)
+				accounts.GET("/:id", controllers.GetAccountByID)
+				accounts.PUT("/:id", controllers.UpdateAccount)
+				accounts.DELETE("/:id", controllers.DeleteAccount)
+				accounts.POST("/:id/members", controllers.InviteAccountMember)
+				accounts.PUT("/:id/members/:userId", controllers.UpdateAccountMember)
+				accounts.DELETE("/:id/members/:userId", controllers.RemoveAccountMember)
+			}
+
+			// Transfer antar akun routes
+			transfers := protected.Group("/transfers")
+			{
+				transfers.POST("", controllers.CreateTransfer)
+				transfers.GET("", controllers.GetTransfers)
+				transfers.DELETE("/:id", controllers.DeleteTransfer)
 			}
 
 			// Financial Goals routes
@@ -52,15 +93,114 @@ func SetupRoutes(router *gin.Engine) {
 				goals.PUT("/:id", controllers.UpdateGoal)
 				goals.POST("/:id/add", controllers.AddProgressToGoal)
 				goals.POST("/:id/withdraw", controllers.WithdrawFromGoal)
+				goals.GET("/:id/history", controllers.GetGoalHistory)
+				goals.GET("/:id/monthly", controllers.GetGoalMonthlySavings)
+				goals.POST("/:id/lock", controllers.LockGoal)
+				goals.POST("/:id/archive", controllers.ArchiveGoal)
+				goals.POST("/:id/restore", controllers.RestoreGoal)
+				goals.POST("/:id/rules", controllers.CreateGoalRule)
+				goals.GET("/:id/rules", controllers.GetGoalRules)
+				goals.PUT("/:id/rules/:ruleId", controllers.UpdateGoalRule)
+				goals.DELETE("/:id/rules/:ruleId", controllers.DeleteGoalRule)
+				goals.POST("/:id/members", controllers.InviteGoalMember)
+				goals.PUT("/:id/members/:userId", controllers.UpdateGoalMember)
+				goals.DELETE("/:id/members/:userId", controllers.RemoveGoalMember)
 				goals.DELETE("/:id", controllers.DeleteGoal)
 			}
 
+			// Invitation (goal & akun bersama) routes
+			invitations := protected.Group("/invitations")
+			{
+				invitations.GET("", controllers.GetInvitations)
+				invitations.POST("/:id/accept", controllers.AcceptInvitation)
+				invitations.POST("/:id/decline", controllers.DeclineInvitation)
+			}
+
+			// Debt (utang/piutang) routes
+			debts := protected.Group("/debts")
+			{
+				debts.POST("", controllers.CreateDebt)
+				debts.GET("", controllers.GetDebts)
+				debts.GET("/:id", controllers.GetDebtByID)
+				debts.PUT("/:id", controllers.UpdateDebt)
+				debts.POST("/:id/payments", controllers.AddDebtPayment)
+				debts.DELETE("/:id", controllers.DeleteDebt)
+			}
+
+			// Installment (cicilan & paylater) routes
+			installments := protected.Group("/installments")
+			{
+				installments.POST("", controllers.CreateInstallment)
+				installments.GET("", controllers.GetInstallments)
+				installments.GET("/upcoming", controllers.GetUpcomingInstallments)
+				installments.GET("/:id", controllers.GetInstallmentByID)
+				installments.PUT("/:id", controllers.UpdateInstallment)
+				installments.DELETE("/:id", controllers.DeleteInstallment)
+			}
+
+			// Bill (tagihan) routes
+			bills := protected.Group("/bills")
+			{
+				bills.POST("", controllers.CreateBill)
+				bills.GET("", controllers.GetBills)
+				bills.GET("/upcoming", controllers.GetUpcomingBills)
+				bills.POST("/calendar", controllers.CreateBillCalendarToken)
+				bills.DELETE("/calendar", controllers.DeleteBillCalendarToken)
+				bills.GET("/:id", controllers.GetBillByID)
+				bills.PUT("/:id", controllers.UpdateBill)
+				bills.POST("/:id/pay", controllers.PayBill)
+				bills.DELETE("/:id", controllers.DeleteBill)
+			}
+
+			// Budget routes
+			budgets := protected.Group("/budgets")
+			{
+				budgets.POST("", controllers.CreateBudget)
+				budgets.GET("", controllers.GetBudgets)
+				budgets.GET("/status", controllers.GetBudgetStatus)
+				budgets.PUT("/:id", controllers.UpdateBudget)
+				budgets.DELETE("/:id", controllers.DeleteBudget)
+			}
+
+			// Exchange rate routes
+			exchangeRates := protected.Group("/exchange-rates")
+			{
+				exchangeRates.POST("", controllers.CreateExchangeRate)
+				exchangeRates.GET("", controllers.GetExchangeRates)
+				exchangeRates.PUT("/:id", controllers.UpdateExchangeRate)
+				exchangeRates.DELETE("/:id", controllers.DeleteExchangeRate)
+			}
+
+			// Notification routes
+			notifications := protected.Group("/notifications")
+			{
+				notifications.GET("", controllers.GetNotifications)
+				notifications.PUT("/read-all", controllers.MarkAllNotificationsRead)
+				notifications.PUT("/:id/read", controllers.MarkNotificationRead)
+			}
+
+			// Recurring transaction routes
+			recurring := protected.Group("/recurring")
+			{
+				recurring.POST("", controllers.CreateRecurringTransaction)
+				recurring.GET("", controllers.GetRecurringTransactions)
+				recurring.POST("/run", controllers.RunRecurringTransactions)
+				recurring.GET("/:id", controllers.GetRecurringTransactionByID)
+				recurring.PUT("/:id", controllers.UpdateRecurringTransaction)
+				recurring.POST("/:id/pause", controllers.PauseRecurringTransaction)
+				recurring.POST("/:id/resume", controllers.ResumeRecurringTransaction)
+				recurring.POST("/:id/skip", controllers.SkipRecurringTransaction)
+				recurring.DELETE("/:id", controllers.DeleteRecurringTransaction)
+			}
+
 			// Statistics routes
 			stats := protected.Group("/stats")
 			{
 				stats.GET("/summary", controllers.GetSummary)
 				stats.GET("/expense-by-category", controllers.GetExpenseByCategory)
+				stats.GET("/income-by-category", controllers.GetIncomeByCategory)
 				stats.GET("/income-vs-expense", controllers.GetIncomeVsExpense)
+				stats.GET("/by-tag", controllers.GetStatsByTag)
 			}
 		}
 	}
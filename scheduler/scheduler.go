@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"DompetKu/controllers"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Start menjalankan pemrosesan transaksi berulang di background: sekali saat
+// server mulai (untuk mengejar jadwal yang terlewat) lalu setiap interval.
+func Start(interval time.Duration) {
+	go func() {
+		run()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
+}
+
+func run() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// Jadwal yang gagal dilewati, jadwal lain tetap diproses
+	created, err := controllers.ProcessDueRecurringTransactions(ctx, bson.M{}, time.Now())
+	if err != nil {
+		log.Println("Failed to process some recurring transactions:", err)
+	}
+	if created > 0 {
+		log.Printf("Created %d recurring transactions", created)
+	}
+}
@@ -2,44 +2,60 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
 	"time"
 )
 
 type ImageKitResponse struct {
-	FileID      string `json:"fileId"`
-	Name        string `json:"name"`
-	URL         string `json:"url"`
+	FileID       string `json:"fileId"`
+	Name         string `json:"name"`
+	URL          string `json:"url"`
 	ThumbnailURL string `json:"thumbnailUrl"`
-	Height      int    `json:"height"`
-	Width       int    `json:"width"`
-	Size        int    `json:"size"`
+	Height       int    `json:"height"`
+	Width        int    `json:"width"`
+	Size         int    `json:"size"`
 }
 
 type ImageKitErrorResponse struct {
 	Message string `json:"message"`
 }
 
-// UploadToImageKit uploads a file to ImageKit and returns the URL
-func UploadToImageKit(file multipart.File, fileHeader *multipart.FileHeader, folder string) (string, error) {
-	privateKey := os.Getenv("IMAGEKIT_PRIVATE_KEY")
-	urlEndpoint := os.Getenv("IMAGEKIT_URL_ENDPOINT")
-	
-	if privateKey == "" || urlEndpoint == "" {
-		return "", fmt.Errorf("ImageKit configuration not found")
+// ImageKitStorage menyimpan file di ImageKit
+type ImageKitStorage struct {
+	privateKey  string
+	urlEndpoint string
+	client      *http.Client
+}
+
+func NewImageKitStorage(privateKey, urlEndpoint string) *ImageKitStorage {
+	return &ImageKitStorage{
+		privateKey:  privateKey,
+		urlEndpoint: urlEndpoint,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *ImageKitStorage) authorization() string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(s.privateKey+":"))
+}
+
+// Upload uploads a file to ImageKit. FileID adalah fileId dari ImageKit.
+func (s *ImageKitStorage) Upload(ctx context.Context, file io.Reader, filename string, folder string) (*StoredFile, error) {
+	if s.privateKey == "" || s.urlEndpoint == "" {
+		return nil, fmt.Errorf("ImageKit configuration not found")
 	}
 
 	// Read file content
 	fileBytes, err := io.ReadAll(file)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file: %v", err)
+		return nil, fmt.Errorf("failed to read file: %v", err)
 	}
 
 	// Create form data
@@ -49,10 +65,6 @@ func UploadToImageKit(file multipart.File, fileHeader *multipart.FileHeader, fol
 	// Add file as base64
 	base64File := base64.StdEncoding.EncodeToString(fileBytes)
 	writer.WriteField("file", base64File)
-
-	// Generate unique filename
-	ext := filepath.Ext(fileHeader.Filename)
-	filename := fmt.Sprintf("profile_%d%s", time.Now().UnixNano(), ext)
 	writer.WriteField("fileName", filename)
 
 	// Set folder
@@ -63,42 +75,65 @@ func UploadToImageKit(file multipart.File, fileHeader *multipart.FileHeader, fol
 	writer.Close()
 
 	// Create request
-	req, err := http.NewRequest("POST", "https://upload.imagekit.io/api/v1/files/upload", body)
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://upload.imagekit.io/api/v1/files/upload", body)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
+		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	// Set headers
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	
-	// Set basic auth with private key
-	auth := base64.StdEncoding.EncodeToString([]byte(privateKey + ":"))
-	req.Header.Set("Authorization", "Basic "+auth)
+	req.Header.Set("Authorization", s.authorization())
 
 	// Send request
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload: %v", err)
+		return nil, fmt.Errorf("failed to upload: %v", err)
 	}
 	defer resp.Body.Close()
 
 	// Read response
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %v", err)
+		return nil, fmt.Errorf("failed to read response: %v", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		var errResp ImageKitErrorResponse
 		json.Unmarshal(respBody, &errResp)
-		return "", fmt.Errorf("upload failed: %s", errResp.Message)
+		return nil, fmt.Errorf("upload failed: %s", errResp.Message)
 	}
 
 	var result ImageKitResponse
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", fmt.Errorf("failed to parse response: %v", err)
+		return nil, fmt.Errorf("failed to parse response: %v", err)
 	}
 
-	return result.URL, nil
+	return &StoredFile{FileID: result.FileID, URL: result.URL}, nil
+}
+
+// Delete deletes an uploaded file by its ImageKit file ID
+func (s *ImageKitStorage) Delete(ctx context.Context, fileID string) error {
+	if s.privateKey == "" {
+		return fmt.Errorf("ImageKit configuration not found")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", "https://api.imagekit.io/v1/files/"+url.PathEscape(fileID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", s.authorization())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// File yang sudah tidak ada dianggap berhasil dihapus
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		var errResp ImageKitErrorResponse
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("delete failed: %s", errResp.Message)
+	}
+	return nil
 }
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorageRoute adalah path tempat file LocalStorage disajikan sebagai static file
+const LocalStorageRoute = "/uploads"
+
+// LocalStorage menyimpan file di disk, cocok untuk development dan testing
+// tanpa layanan eksternal. File disajikan oleh server di LocalStorageRoute.
+type LocalStorage struct {
+	Dir     string
+	BaseURL string // misalnya "http://localhost:8080", kosong untuk URL relatif
+}
+
+func NewLocalStorage(dir, baseURL string) *LocalStorage {
+	return &LocalStorage{Dir: dir, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// key membersihkan fileID dan menolak path yang keluar dari Dir
+func (s *LocalStorage) key(fileID string) (string, error) {
+	key := path.Clean("/" + filepath.ToSlash(fileID))[1:]
+	if key == "" || key != filepath.ToSlash(fileID) {
+		return "", fmt.Errorf("invalid file ID %q", fileID)
+	}
+	return key, nil
+}
+
+// Upload writes the file to Dir/folder/filename. FileID adalah path relatif terhadap Dir.
+func (s *LocalStorage) Upload(ctx context.Context, file io.Reader, filename string, folder string) (*StoredFile, error) {
+	key, err := s.key(path.Join(folder, filename))
+	if err != nil {
+		return nil, err
+	}
+
+	fullPath := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create folder: %v", err)
+	}
+
+	out, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %v", err)
+	}
+	if _, err := io.Copy(out, file); err != nil {
+		out.Close()
+		os.Remove(fullPath)
+		return nil, fmt.Errorf("failed to write file: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(fullPath)
+		return nil, fmt.Errorf("failed to write file: %v", err)
+	}
+
+	return &StoredFile{FileID: key, URL: s.BaseURL + LocalStorageRoute + "/" + key}, nil
+}
+
+// Delete removes the file, a missing file is not an error
+func (s *LocalStorage) Delete(ctx context.Context, fileID string) error {
+	key, err := s.key(fileID)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(s.Dir, filepath.FromSlash(key)))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %v", err)
+	}
+	return nil
+}
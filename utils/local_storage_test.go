@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorageKey(t *testing.T) {
+	s := NewLocalStorage(t.TempDir(), "")
+
+	valid := map[string]string{
+		"receipt.jpg":                   "receipt.jpg",
+		"Dompetku/receipts/receipt.jpg": "Dompetku/receipts/receipt.jpg",
+	}
+	for fileID, want := range valid {
+		got, err := s.key(fileID)
+		if err != nil {
+			t.Errorf("key(%q) returned error: %v", fileID, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("key(%q) = %q, want %q", fileID, got, want)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"..",
+		"../secret.txt",
+		"Dompetku/../../secret.txt",
+		"Dompetku/./receipt.jpg",
+		"/etc/passwd",
+		"Dompetku//receipt.jpg",
+		"Dompetku/receipts/",
+	}
+	for _, fileID := range invalid {
+		if got, err := s.key(fileID); err == nil {
+			t.Errorf("key(%q) = %q, want error", fileID, got)
+		}
+	}
+}
+
+func TestLocalStorageUploadAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	s := NewLocalStorage(dir, "http://localhost:8080/")
+	ctx := context.Background()
+
+	stored, err := s.Upload(ctx, strings.NewReader("struk"), "receipt_1.jpg", "Dompetku/receipts")
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if stored.FileID != "Dompetku/receipts/receipt_1.jpg" {
+		t.Errorf("FileID = %q", stored.FileID)
+	}
+	if stored.URL != "http://localhost:8080/uploads/Dompetku/receipts/receipt_1.jpg" {
+		t.Errorf("URL = %q", stored.URL)
+	}
+
+	path := filepath.Join(dir, "Dompetku", "receipts", "receipt_1.jpg")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read uploaded file: %v", err)
+	}
+	if string(data) != "struk" {
+		t.Errorf("file content = %q, want %q", data, "struk")
+	}
+
+	// Nama file yang sama tidak boleh menimpa file lain
+	if _, err := s.Upload(ctx, strings.NewReader("lain"), "receipt_1.jpg", "Dompetku/receipts"); err == nil {
+		t.Errorf("second Upload with the same name succeeded")
+	}
+
+	if _, err := s.Upload(ctx, strings.NewReader("x"), "escape.jpg", ".."); err == nil {
+		t.Errorf("Upload outside Dir succeeded")
+	}
+
+	if err := s.Delete(ctx, stored.FileID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file still exists after Delete: %v", err)
+	}
+	if err := s.Delete(ctx, stored.FileID); err != nil {
+		t.Errorf("Delete of a missing file: %v", err)
+	}
+	if err := s.Delete(ctx, "../outside.jpg"); err == nil {
+		t.Errorf("Delete outside Dir succeeded")
+	}
+}
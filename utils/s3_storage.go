@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config adalah konfigurasi storage S3-compatible (AWS S3, MinIO, R2)
+type S3Config struct {
+	Endpoint        string // misalnya "http://localhost:9000" untuk MinIO
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PublicURL       string // base URL publik bucket, default Endpoint/Bucket
+}
+
+// S3Storage menyimpan file di bucket S3-compatible memakai path-style URL
+// (Endpoint/Bucket/key) dan request yang ditandatangani AWS Signature V4
+type S3Storage struct {
+	config S3Config
+	client *http.Client
+}
+
+func NewS3Storage(config S3Config) *S3Storage {
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+	config.PublicURL = strings.TrimRight(config.PublicURL, "/")
+	return &S3Storage{config: config, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (s *S3Storage) objectURL(key string) string {
+	return s.config.Endpoint + "/" + s3URIEncode(s.config.Bucket) + "/" + s3URIEncode(key)
+}
+
+// Upload puts the object at folder/filename. FileID adalah object key.
+func (s *S3Storage) Upload(ctx context.Context, file io.Reader, filename string, folder string) (*StoredFile, error) {
+	if s.config.Endpoint == "" || s.config.Bucket == "" || s.config.AccessKeyID == "" || s.config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 configuration not found")
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	key := strings.TrimPrefix(path.Join(folder, filename), "/")
+	req, err := http.NewRequestWithContext(ctx, "PUT", s.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", http.DetectContentType(data))
+	s.sign(req, data, time.Now())
+
+	if err := s.do(req, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("upload failed: %v", err)
+	}
+
+	publicURL := s.config.PublicURL
+	if publicURL == "" {
+		publicURL = s.config.Endpoint + "/" + s3URIEncode(s.config.Bucket)
+	}
+	return &StoredFile{FileID: key, URL: publicURL + "/" + s3URIEncode(key)}, nil
+}
+
+// Delete deletes the object, S3 also succeeds for a missing key
+func (s *S3Storage) Delete(ctx context.Context, fileID string) error {
+	if s.config.Endpoint == "" || s.config.Bucket == "" || s.config.AccessKeyID == "" || s.config.SecretAccessKey == "" {
+		return fmt.Errorf("S3 configuration not found")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", s.objectURL(fileID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	s.sign(req, nil, time.Now())
+
+	if err := s.do(req, http.StatusNoContent, http.StatusOK, http.StatusNotFound); err != nil {
+		return fmt.Errorf("delete failed: %v", err)
+	}
+	return nil
+}
+
+func (s *S3Storage) do(req *http.Request, okStatuses ...int) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	for _, status := range okStatuses {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
+
+// sign menambahkan header Authorization AWS Signature Version 4
+func (s *S3Storage) sign(req *http.Request, payload []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery mengurutkan dan meng-encode query string sesuai aturan SigV4
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var params []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, s3QueryEncode(key)+"="+s3QueryEncode(value))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+func s3QueryEncode(value string) string {
+	return strings.ReplaceAll(s3URIEncode(value), "/", "%2F")
+}
+
+// s3URIEncode meng-encode setiap segmen path sesuai aturan SigV4: hanya
+// karakter unreserved yang dibiarkan, "/" tetap sebagai pemisah
+func s3URIEncode(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
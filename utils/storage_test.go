@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewStorageFromEnv(t *testing.T) {
+	t.Setenv("STORAGE_DRIVER", "local")
+	t.Setenv("LOCAL_STORAGE_DIR", "/tmp/dompetku")
+	s, err := NewStorageFromEnv()
+	if err != nil {
+		t.Fatalf("local: %v", err)
+	}
+	if local, ok := s.(*LocalStorage); !ok || local.Dir != "/tmp/dompetku" {
+		t.Errorf("local: got %#v", s)
+	}
+
+	t.Setenv("STORAGE_DRIVER", "S3")
+	if s, err := NewStorageFromEnv(); err != nil {
+		t.Errorf("s3: %v", err)
+	} else if _, ok := s.(*S3Storage); !ok {
+		t.Errorf("s3: got %T", s)
+	}
+
+	t.Setenv("STORAGE_DRIVER", "")
+	if s, err := NewStorageFromEnv(); err != nil {
+		t.Errorf("default: %v", err)
+	} else if _, ok := s.(*ImageKitStorage); !ok {
+		t.Errorf("default: got %T", s)
+	}
+
+	t.Setenv("STORAGE_DRIVER", "ftp")
+	if _, err := NewStorageFromEnv(); err == nil {
+		t.Errorf("unknown driver: expected error")
+	}
+}
+
+func TestUniqueFileName(t *testing.T) {
+	tests := map[string]*regexp.Regexp{
+		"struk.JPG":         regexp.MustCompile(`^receipt_\d+\.jpg$`),
+		"struk":             regexp.MustCompile(`^receipt_\d+$`),
+		"../../etc/passwd":  regexp.MustCompile(`^receipt_\d+$`),
+		"foto.php%00.png":   regexp.MustCompile(`^receipt_\d+\.png$`),
+		"struk.verylongext": regexp.MustCompile(`^receipt_\d+$`),
+	}
+	for original, want := range tests {
+		if got := UniqueFileName("receipt", original); !want.MatchString(got) {
+			t.Errorf("UniqueFileName(%q) = %q, want match %s", original, got, want)
+		}
+	}
+}